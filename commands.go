@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/PhelGc/furina-sync/internal/config"
+	"github.com/PhelGc/furina-sync/internal/jira"
+)
+
+// usage describe los comandos disponibles además de la ejecución normal
+const usage = `Uso:
+  furina-sync                 inicia la sincronización
+  furina-sync config check    valida la configuración y muestra la efectiva (sin secretos)
+  furina-sync migrate status  lista las migraciones de la base de datos y si están aplicadas
+  furina-sync migrate up      aplica las migraciones pendientes`
+
+// runCommand ejecuta un comando de línea de comandos y devuelve el código de salida
+func runCommand(args []string) int {
+	switch strings.Join(args, " ") {
+	case "config check":
+		return configCheck()
+	case "migrate status":
+		return migrate(false)
+	case "migrate up":
+		return migrate(true)
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Comando desconocido: %s\n\n%s\n", strings.Join(args, " "), usage)
+		return 2
+	}
+}
+
+// configCheck carga la configuración igual que al iniciar, imprime la
+// configuración efectiva con los secretos ocultos y lista todos los problemas,
+// incluidos los de los horarios (ver loadSchedules)
+func configCheck() int {
+	cfg, err := config.Load()
+	problems := unwrapAll(err)
+	if _, schedErr := loadSchedules(cfg); schedErr != nil {
+		problems = append(problems, unwrapAll(schedErr)...)
+	}
+
+	fmt.Printf("# Archivo de configuración: %s", config.File())
+	if _, statErr := os.Stat(config.File()); statErr != nil {
+		fmt.Print(" (no existe, solo variables de entorno)")
+	}
+	fmt.Println()
+
+	out, yamlErr := cfg.Redacted().YAML()
+	if yamlErr != nil {
+		fmt.Fprintf(os.Stderr, "Error serializando configuración: %v\n", yamlErr)
+		return 1
+	}
+	fmt.Print(out)
+
+	if len(problems) == 0 {
+		fmt.Fprintln(os.Stderr, "Configuración válida")
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "\n%d problema(s) en la configuración:\n", len(problems))
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "  - %v\n", problem)
+	}
+	return 1
+}
+
+// unwrapAll separa los errores unidos con errors.Join
+func unwrapAll(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// migrate muestra el estado de las migraciones y, si up es true, aplica las
+// pendientes antes. Al iniciar el bot también se aplican automáticamente. Solo
+// necesita la configuración de la base de datos; si además está la de Jira, la
+// migración 0007 la usa para traducir nombres visibles a accountId.
+func migrate(up bool) int {
+	cfg, err := config.LoadDatabase()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Configuración de la base de datos inválida (ver config check):\n%v\n", err)
+		return 1
+	}
+	var resolve func(string) (string, error)
+	if cfg.Jira.URL != "" && cfg.Jira.Username != "" && cfg.Jira.APIToken != "" {
+		jiraClient, err := jira.NewClient(cfg.Jira)
+		if err == nil {
+			resolve = assigneeResolver(jiraClient)
+		}
+	}
+	dbClient, err := openDatabase(cfg, resolve)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error conectando a la base de datos: %v\n", err)
+		return 1
+	}
+	defer dbClient.Close()
+
+	if up {
+		if err := dbClient.Migrate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error aplicando migraciones: %v\n", err)
+			return 1
+		}
+	}
+
+	states, err := dbClient.MigrationStatus()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error consultando migraciones: %v\n", err)
+		return 1
+	}
+	pending := 0
+	for _, state := range states {
+		switch {
+		case state.Unknown:
+			fmt.Printf("%04d  %-30s  aplicada %s (desconocida: de una versión más nueva)\n",
+				state.Version, state.Name, state.AppliedAt.Format("2006-01-02 15:04:05"))
+		case state.Applied:
+			fmt.Printf("%04d  %-30s  aplicada %s\n", state.Version, state.Name, state.AppliedAt.Format("2006-01-02 15:04:05"))
+		default:
+			fmt.Printf("%04d  %-30s  pendiente\n", state.Version, state.Name)
+			pending++
+		}
+	}
+	if pending > 0 {
+		fmt.Fprintf(os.Stderr, "%d migración(es) pendiente(s): ejecuta migrate up\n", pending)
+	}
+	return 0
+}
@@ -1,157 +1,451 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
 // Config contiene toda la configuración del sistema
 type Config struct {
-	Jira     JiraConfig
-	Sync     SyncConfig
-	Storage  StorageConfig
-	Discord  DiscordConfig
-	Database DatabaseConfig
-	Eval     EvalConfig
+	Jira     JiraConfig     `yaml:"jira"`
+	Sync     SyncConfig     `yaml:"sync"`
+	Storage  StorageConfig  `yaml:"storage"`
+	Discord  DiscordConfig  `yaml:"discord"`
+	Database DatabaseConfig `yaml:"database"`
+	Eval     EvalConfig     `yaml:"eval"`
+	Leader   LeaderConfig   `yaml:"leader"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
+	Schedule ScheduleConfig `yaml:"schedule"`
+	Report   ReportConfig   `yaml:"report"`
 }
 
+// Políticas de re-evaluación cuando cambia la versión de los prompts
+const (
+	ReevalAll     = "all"     // re-evaluar todas las incidencias abiertas en el siguiente ciclo
+	ReevalGradual = "gradual" // re-evaluar como máximo ReevalMaxPerTick por ciclo
+	ReevalNone    = "none"    // conservar evaluaciones anteriores hasta que cambie el contenido
+)
+
+// Políticas de entrega para assignees sin canal en DISCORD_CHANNELS
+const (
+	UnmappedDM       = "dm"       // mensaje directo al usuario de DISCORD_USERS; si no hay, canal de respaldo
+	UnmappedFallback = "fallback" // canal de respaldo (DISCORD_FALLBACK_CHANNEL)
+	UnmappedSkip     = "skip"     // no enviar; la evaluación se guarda igual
+)
+
+// Modos de recordatorio (DISCORD_REMINDER_MODE)
+const (
+	ReminderReply  = "reply"  // respuesta al mensaje de la evaluación que menciona al assignee
+	ReminderRepost = "repost" // se vuelve a publicar la evaluación y se borra la anterior
+)
+
 // EvalConfig configuración del evaluador IA (Gemini)
 type EvalConfig struct {
-	Enabled      bool
-	APIKey       string
-	Model        string
-	PromptPhase1 string // ruta al archivo de prompt fase 1
-	PromptPhase2 string // ruta al archivo de prompt fase 2
+	Enabled          bool          `yaml:"enabled"`
+	APIKey           string        `yaml:"api_key"` // solo por entorno: en el archivo es un error
+	APIKeyFile       string        `yaml:"api_key_file,omitempty"`
+	APIKeyCommand    string        `yaml:"api_key_command,omitempty"`
+	Model            string        `yaml:"model"`
+	PromptPhase1     string        `yaml:"prompt_phase1"`           // ruta al archivo de prompt fase 1
+	PromptPhase2     string        `yaml:"prompt_phase2"`           // ruta al archivo de prompt fase 2
+	ReevalPolicy     string        `yaml:"reeval_on_prompt_change"` // all / gradual / none
+	ReevalMaxPerTick int           `yaml:"reeval_max_per_tick"`     // límite por ciclo para la política gradual
+	PipelineFile     string        `yaml:"pipeline_file"`           // pipeline.json del set por defecto (vacío = fases descripción y conclusión)
+	PromptSetsDir    string        `yaml:"prompt_sets_dir"`         // directorio con un subdirectorio por set de prompts
+	Routes           []PromptRoute `yaml:"prompt_routes"`           // primera ruta que coincide con la incidencia
+	ScoreThreshold   int           `yaml:"score_threshold"`         // puntaje mínimo aceptable del set por defecto
+	HashComments     bool          `yaml:"hash_comments"`           // si los comentarios cuentan para el hash de caché
+}
+
+// PromptRoute asigna un set de prompts y un umbral de puntaje a un tipo de
+// incidencia, opcionalmente limitado a un proyecto y a un componente
+type PromptRoute struct {
+	IssueType string `yaml:"issue_type"`
+	Project   string `yaml:"project,omitempty"`   // vacío = cualquier proyecto
+	Component string `yaml:"component,omitempty"` // vacío = cualquier componente
+	Set       string `yaml:"set"`                 // nombre del subdirectorio en PromptSetsDir
+	Threshold int    `yaml:"threshold,omitempty"` // 0 = usar ScoreThreshold
 }
 
 // JiraConfig configuración de conexión a Jira
 type JiraConfig struct {
-	URL           string
-	Username      string
-	APIToken      string
-	Project       string
-	Status        string // Estado específico a buscar
-	Assignee      string // Nombre de la persona asignada
-	CurrentSprint bool   // Si buscar solo en el sprint actual
+	URL             string `yaml:"url"`
+	Username        string `yaml:"username"`
+	APIToken        string `yaml:"api_token"` // solo por entorno: en el archivo es un error
+	APITokenFile    string `yaml:"api_token_file,omitempty"`
+	APITokenCommand string `yaml:"api_token_command,omitempty"`
+	Project         string `yaml:"project"`
+	Status          string `yaml:"status"`             // Estado específico a buscar
+	Assignee        string `yaml:"assignee"`           // Nombre de la persona asignada
+	CurrentSprint   bool   `yaml:"current_sprint"`     // Si buscar solo en el sprint actual
+	FetchComments   bool   `yaml:"fetch_comments"`     // Si descargar comentarios como contexto para el evaluador
+	FetchWorklog    bool   `yaml:"fetch_worklog"`      // Si descargar los comentarios del worklog
+	CommentsMax     int    `yaml:"comments_max"`       // Máximo de comentarios (más recientes primero)
+	CommentsChars   int    `yaml:"comments_max_chars"` // Máximo de caracteres del extracto de comentarios
 }
 
 // SyncConfig configuración de sincronización
 type SyncConfig struct {
-	IntervalMinutes int
+	Interval                 string `yaml:"interval_minutes"`           // minutos entre sincronizaciones, o expresiones cron (ver schedule.ParseSpec)
+	WatchIntervalSeconds     int    `yaml:"watch_interval_seconds"`     // cada cuánto revisar cambios en prompts y .env (0 = solo SIGHUP)
+	ReconcileIntervalMinutes int    `yaml:"reconcile_interval_minutes"` // cada cuánto comparar Discord con la BD (0 = nunca)
+}
+
+// ScheduleConfig horario laboral: fuera de él las entregas a Discord esperan
+type ScheduleConfig struct {
+	Timezone     string `yaml:"timezone"`      // zona IANA del horario y de las expresiones cron (vacío = hora local)
+	WorkDays     string `yaml:"work_days"`     // p. ej. mon-fri
+	WorkHours    string `yaml:"work_hours"`    // p. ej. 09:00-18:00 (vacío = sin horario, se entrega siempre)
+	HolidaysFile string `yaml:"holidays_file"` // una fecha YYYY-MM-DD por línea (opcional)
+}
+
+// Períodos de los resúmenes
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// Tipos de resumen
+const (
+	DigestSummary     = "summary"     // evaluaciones del período, promedios y peores puntajes
+	DigestLeaderboard = "leaderboard" // estadísticas por equipo y assignee con gráfico de tendencia
+)
+
+// ReportConfig resúmenes periódicos de evaluaciones (solo en el archivo YAML)
+type ReportConfig struct {
+	Teams   map[string][]string `yaml:"teams"`   // equipo → accountIds (o nombres visibles) de sus integrantes
+	Digests []DigestConfig      `yaml:"digests"` // resúmenes a publicar
+}
+
+// DigestConfig un resumen periódico publicado en un canal
+type DigestConfig struct {
+	Name       string `yaml:"name"`       // identifica el resumen en digest_runs; si cambia, empieza de cero
+	Type       string `yaml:"type"`       // summary / leaderboard
+	Channel    string `yaml:"channel"`    // canal donde se publica
+	Period     string `yaml:"period"`     // daily / weekly; fija el horario por defecto y el período del primer resumen
+	Schedule   string `yaml:"schedule"`   // cuándo se publica, como SYNC_INTERVAL_MINUTES (vacío = según el período)
+	Team       string `yaml:"team"`       // equipo de Teams (vacío = todas las incidencias)
+	Worst      int    `yaml:"worst"`      // summary: peores incidencias listadas (por defecto 5)
+	Percentile int    `yaml:"percentile"` // leaderboard: percentil del puntaje (por defecto 90)
+	Periods    int    `yaml:"periods"`    // leaderboard: períodos en el gráfico de tendencia (por defecto 8)
+}
+
+// SpecValue devuelve el horario del resumen: el configurado o uno por defecto
+// según el período (todos los días o los lunes a las 9:00)
+func (d *DigestConfig) SpecValue() string {
+	switch {
+	case d.Schedule != "":
+		return d.Schedule
+	case d.Period == DigestWeekly:
+		return "0 9 * * mon"
+	default:
+		return "0 9 * * *"
+	}
+}
+
+// Lookback es el período que cubre el primer resumen, cuando todavía no hay uno anterior
+func (d *DigestConfig) Lookback() time.Duration {
+	if d.Period == DigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
 }
 
 // StorageConfig configuración de almacenamiento
 type StorageConfig struct {
-	BasePath string // Directorio base para archivos individuales
+	BasePath string `yaml:"base_path"` // Directorio base para archivos individuales
 }
 
 // DiscordConfig configuración del bot de Discord
 type DiscordConfig struct {
-	BotToken                string
-	GuildID                 string
-	Channels                map[string]string // Map de assignee -> channel ID
-	RenotifyIntervalMinutes int               // Tiempo en minutos para re-notificar
+	BotToken                string            `yaml:"bot_token"` // solo por entorno: en el archivo es un error
+	BotTokenFile            string            `yaml:"bot_token_file,omitempty"`
+	BotTokenCommand         string            `yaml:"bot_token_command,omitempty"`
+	GuildID                 string            `yaml:"guild_id"`
+	Channels                map[string]string `yaml:"channels"`                  // Map de accountId (o nombre visible, obsoleto) -> channel ID
+	RoutesFile              string            `yaml:"routes_file"`               // Reglas de ruteo en JSON, se evalúan antes que Channels
+	RenotifyIntervalMinutes int               `yaml:"renotify_interval_minutes"` // Minutos sin cambios antes de un recordatorio (0 = sin recordatorios)
+	ReminderMode            string            `yaml:"reminder_mode"`             // reply / repost
+	ReminderMax             int               `yaml:"reminder_max"`              // recordatorios por mensaje (0 = sin límite)
+	ReminderBelowScore      int               `yaml:"reminder_below_score"`      // puntaje bajo el cual se recuerda (0 = umbral del set)
+	QuietHours              string            `yaml:"quiet_hours"`               // franja sin recordatorios, p. ej. 22:00-08:00
+	HandoverNotice          bool              `yaml:"handover_notice"`           // Avisar en ambos canales cuando una incidencia se reasigna
+	FallbackChannel         string            `yaml:"fallback_channel"`          // Canal para assignees sin canal propio e incidencias sin assignee
+	Users                   map[string]string `yaml:"users"`                     // Map de accountId -> ID de usuario de Discord (mensajes directos)
+	UnmappedPolicy          string            `yaml:"unmapped_policy"`           // dm / fallback / skip
 }
 
-// DatabaseConfig configuración de la base de datos MySQL
+// Motores de base de datos (DB_DRIVER)
+const (
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// DatabaseConfig configuración de la base de datos
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	Username string
-	Password string
-	Database string
+	Driver          string `yaml:"driver"`   // mysql / sqlite / postgres
+	Path            string `yaml:"path"`     // archivo de SQLite (":memory:" = en memoria)
+	SSLMode         string `yaml:"ssl_mode"` // sslmode de PostgreSQL (vacío = require)
+	Host            string `yaml:"host"`
+	Port            string `yaml:"port"` // vacío = 3306 en MySQL, 5432 en PostgreSQL
+	Username        string `yaml:"username"`
+	Password        string `yaml:"password"` // solo por entorno: en el archivo es un error
+	PasswordFile    string `yaml:"password_file,omitempty"`
+	PasswordCommand string `yaml:"password_command,omitempty"`
+	Database        string `yaml:"database"`
 }
 
-// Load carga la configuración desde variables de entorno
+// LeaderConfig configuración de la elección de líder entre instancias
+type LeaderConfig struct {
+	LeaseSeconds int    `yaml:"lease_seconds"` // duración del lease (0 = sin elección, siempre líder)
+	ID           string `yaml:"id"`            // identidad de la instancia (vacío = hostname-pid-aleatorio)
+}
+
+// MetricsConfig configuración del endpoint de métricas
+type MetricsConfig struct {
+	Addr string `yaml:"addr"` // host:puerto donde se publica /debug/vars (vacío = sin métricas)
+}
+
+// EnvFile es el archivo de configuración local que se lee al iniciar y en cada recarga
+const EnvFile = ".env"
+
+// DefaultFile es el archivo de configuración estructurada que se usa si
+// CONFIG_FILE no indica otro. Es opcional.
+const DefaultFile = "config.yaml"
+
+// File devuelve la ruta del archivo de configuración estructurada
+func File() string {
+	return getEnvOrDefault("CONFIG_FILE", DefaultFile)
+}
+
+// processEnv son las variables definidas en el entorno del proceso antes de
+// leer .env. Tienen prioridad sobre el archivo, también al recargar.
+var (
+	processEnv     map[string]bool
+	processEnvOnce sync.Once
+)
+
+func rememberProcessEnv() {
+	processEnvOnce.Do(func() {
+		processEnv = make(map[string]bool)
+		for _, pair := range os.Environ() {
+			if key, _, ok := strings.Cut(pair, "="); ok {
+				processEnv[key] = true
+			}
+		}
+	})
+}
+
+// Load carga la configuración: valores por defecto, luego el archivo YAML (si
+// existe) y por último las variables de entorno, que tienen prioridad. Si hay
+// problemas los devuelve todos juntos, además de la configuración tal como
+// quedó para poder mostrarla (ver el comando config check).
 func Load() (*Config, error) {
+	rememberProcessEnv()
 	// Cargar archivo .env si existe
-	godotenv.Load()
+	godotenv.Load(EnvFile)
+
+	return build()
+}
 
-	intervalMinutes := 5 // por defecto cada 5 minutos
-	if interval := os.Getenv("SYNC_INTERVAL_MINUTES"); interval != "" {
-		if parsed, err := strconv.Atoi(interval); err == nil {
-			intervalMinutes = parsed
+// LoadDatabase carga la configuración igual que Load pero solo valida la base
+// de datos: es para los comandos que no usan Discord ni el evaluador (migrate),
+// que así no exigen sus credenciales
+func LoadDatabase() (*Config, error) {
+	rememberProcessEnv()
+	godotenv.Load(EnvFile)
+
+	config := defaults()
+	var errs []error
+	if err := config.loadFile(File()); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, config.applyEnv()...)
+	config.driverDefaults()
+	errs = append(errs, config.validateDatabase()...)
+
+	return config, errors.Join(errs...)
+}
+
+// Reload vuelve a leer el archivo .env y construye una configuración nueva.
+// Como en Load, las variables del entorno del proceso tienen prioridad sobre
+// el archivo; las que vienen de .env se actualizan. Las variables eliminadas
+// del archivo conservan su valor anterior hasta reiniciar el proceso.
+func Reload() (*Config, error) {
+	rememberProcessEnv()
+	values, err := godotenv.Read(EnvFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error leyendo %s: %v", EnvFile, err)
+	}
+	for key, value := range values {
+		if !processEnv[key] {
+			os.Setenv(key, value)
 		}
 	}
 
-	// Parsear configuración de Discord
-	discordChannels := parseDiscordChannels()
-	renotifyInterval, _ := strconv.Atoi(getEnvOrDefault("DISCORD_RENOTIFY_INTERVAL_MINUTES", "60"))
+	return build()
+}
+
+// build construye la configuración a partir del archivo y de las variables de entorno actuales
+func build() (*Config, error) {
+	config := defaults()
 
-	config := &Config{
+	var errs []error
+	if err := config.loadFile(File()); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, config.applyEnv()...)
+	config.driverDefaults()
+	errs = append(errs, config.validate()...)
+
+	return config, errors.Join(errs...)
+}
+
+// defaults devuelve la configuración con los valores por defecto
+func defaults() *Config {
+	return &Config{
 		Jira: JiraConfig{
-			URL:           os.Getenv("JIRA_URL"),
-			Username:      os.Getenv("JIRA_USERNAME"),
-			APIToken:      os.Getenv("JIRA_API_TOKEN"),
-			Project:       os.Getenv("JIRA_PROJECT"),
-			Status:        os.Getenv("JIRA_STATUS"),
-			Assignee:      os.Getenv("JIRA_ASSIGNEE"),
-			CurrentSprint: os.Getenv("JIRA_CURRENT_SPRINT") == "true",
+			CommentsMax:   20,
+			CommentsChars: 4000,
 		},
 		Sync: SyncConfig{
-			IntervalMinutes: intervalMinutes,
+			Interval:                 "5", // por defecto cada 5 minutos
+			WatchIntervalSeconds:     10,
+			ReconcileIntervalMinutes: 60,
 		},
 		Storage: StorageConfig{
-			BasePath: getEnvOrDefault("STORAGE_BASE_PATH", "data/incidents"),
+			BasePath: "data/incidents",
 		},
 		Discord: DiscordConfig{
-			BotToken:                os.Getenv("DISCORD_BOT_TOKEN"),
-			GuildID:                 os.Getenv("DISCORD_GUILD_ID"),
-			Channels:                discordChannels,
-			RenotifyIntervalMinutes: renotifyInterval,
+			Channels:       map[string]string{},
+			Users:          map[string]string{},
+			ReminderMode:   ReminderReply,
+			ReminderMax:    3,
+			UnmappedPolicy: UnmappedFallback,
 		},
 		Database: DatabaseConfig{
-			Host:     getEnvOrDefault("DB_HOST", "localhost"),
-			Port:     getEnvOrDefault("DB_PORT", "3306"),
-			Username: os.Getenv("DB_USERNAME"),
-			Password: os.Getenv("DB_PASSWORD"),
-			Database: getEnvOrDefault("DB_DATABASE", "furina_sync"),
+			Driver:   DriverMySQL,
+			Path:     "data/furina_sync.db",
+			Host:     "localhost",
+			Database: "furina_sync",
 		},
 		Eval: EvalConfig{
-			Enabled:      os.Getenv("EVAL_ENABLED") == "true",
-			APIKey:       os.Getenv("GEMINI_API_KEY"),
-			Model:        getEnvOrDefault("EVAL_MODEL", "gemini-2.0-flash"),
-			PromptPhase1: getEnvOrDefault("EVAL_PROMPT_PHASE1", "prompts/phase1.txt"),
-			PromptPhase2: getEnvOrDefault("EVAL_PROMPT_PHASE2", "prompts/phase2.txt"),
+			Model:            "gemini-2.0-flash",
+			PromptPhase1:     "prompts/phase1.txt",
+			PromptPhase2:     "prompts/phase2.txt",
+			ReevalPolicy:     ReevalAll,
+			ReevalMaxPerTick: 10,
+			PromptSetsDir:    "prompts",
+			ScoreThreshold:   60,
+		},
+		Leader: LeaderConfig{
+			LeaseSeconds: 30,
+		},
+		Schedule: ScheduleConfig{
+			WorkDays: "mon-fri",
 		},
 	}
-
-	return config, nil
 }
 
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// loadFile mezcla el archivo YAML sobre la configuración actual. Los campos que
+// el archivo no menciona conservan su valor. Si el archivo no existe no hace nada.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error leyendo %s: %v", path, err)
 	}
-	return defaultValue
-}
 
-// parseDiscordChannels parsea los canales de Discord desde variables de entorno
-// Formato esperado: DISCORD_CHANNELS="assignee1:channelID1,assignee2:channelID2"
-func parseDiscordChannels() map[string]string {
-	channels := make(map[string]string)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true) // una clave mal escrita es un error, no un valor ignorado
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if err := c.inlineSecrets(path); err != nil {
+		return err
+	}
 
-	channelsEnv := os.Getenv("DISCORD_CHANNELS")
-	if channelsEnv == "" {
-		return channels
+	// Normalizar igual que las variables de entorno
+	c.Eval.ReevalPolicy = strings.ToLower(c.Eval.ReevalPolicy)
+	c.Discord.UnmappedPolicy = strings.ToLower(c.Discord.UnmappedPolicy)
+	c.Discord.ReminderMode = strings.ToLower(c.Discord.ReminderMode)
+	c.Database.Driver = strings.ToLower(c.Database.Driver)
+	for i := range c.Report.Digests {
+		digest := &c.Report.Digests[i]
+		digest.Type = strings.ToLower(digest.Type)
+		if digest.Type == "" {
+			digest.Type = DigestSummary
+		}
+		digest.Period = strings.ToLower(digest.Period)
+		if digest.Period == "" {
+			digest.Period = DigestDaily
+		}
+		if digest.Worst == 0 {
+			digest.Worst = 5
+		}
+		if digest.Percentile == 0 {
+			digest.Percentile = 90
+		}
+		if digest.Periods == 0 {
+			digest.Periods = 8
+		}
+	}
+	if c.Discord.Channels == nil {
+		c.Discord.Channels = map[string]string{}
+	}
+	if c.Discord.Users == nil {
+		c.Discord.Users = map[string]string{}
 	}
+	return nil
+}
 
-	// Dividir por comas para obtener cada asignación
-	pairs := strings.Split(channelsEnv, ",")
-	for _, pair := range pairs {
-		// Dividir cada par por dos puntos
-		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
-		if len(parts) == 2 {
-			assignee := strings.TrimSpace(parts[0])
-			channelID := strings.TrimSpace(parts[1])
-			if assignee != "" && channelID != "" {
-				channels[assignee] = channelID
-			}
+// inlineSecrets rechaza los secretos escritos directamente en el archivo, que
+// suele versionarse o copiarse: van en variables de entorno o como referencia
+// (<clave>_file o <clave>_command, ver Secretos en el README)
+func (c *Config) inlineSecrets(path string) error {
+	var errs []error
+	for _, secret := range []struct{ key, value string }{
+		{"jira.api_token", c.Jira.APIToken},
+		{"discord.bot_token", c.Discord.BotToken},
+		{"database.password", c.Database.Password},
+		{"eval.api_key", c.Eval.APIKey},
+	} {
+		if secret.value != "" {
+			errs = append(errs, fmt.Errorf("%s: %s no se admite en el archivo; usar %s_file, %s_command o la variable de entorno",
+				path, secret.key, secret.key, secret.key))
 		}
 	}
+	return errors.Join(errs...)
+}
+
+// driverDefaults completa los valores que dependen del motor de base de datos
+func (c *Config) driverDefaults() {
+	if c.Database.Port != "" {
+		return
+	}
+	switch c.Database.Driver {
+	case DriverMySQL:
+		c.Database.Port = "3306"
+	case DriverPostgres:
+		c.Database.Port = "5432"
+	}
+}
 
-	return channels
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
 }
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// envReader aplica variables de entorno sobre la configuración y acumula los
+// valores que no se pudieron interpretar. Las variables vacías o sin definir no
+// cambian nada: el valor del archivo o el por defecto se conserva.
+type envReader struct {
+	errs []error
+}
+
+func (r *envReader) str(key string, dst *string) {
+	if value := os.Getenv(key); value != "" {
+		*dst = value
+	}
+}
+
+func (r *envReader) lower(key string, dst *string) {
+	if value := os.Getenv(key); value != "" {
+		*dst = strings.ToLower(value)
+	}
+}
+
+func (r *envReader) int(key string, dst *int) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s: %q no es un número entero", key, value))
+		return
+	}
+	*dst = parsed
+}
+
+func (r *envReader) bool(key string, dst *bool) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s: %q no es un booleano (true/false)", key, value))
+		return
+	}
+	*dst = parsed
+}
+
+// accountMap reemplaza el mapa completo si la variable está definida
+func (r *envReader) accountMap(key string, dst *map[string]string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	parsed, err := parseAccountMap(value)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s: %v", key, err))
+	}
+	*dst = parsed
+}
+
+// applyEnv aplica las variables de entorno, que tienen prioridad sobre el archivo
+func (c *Config) applyEnv() []error {
+	r := &envReader{}
+
+	r.str("JIRA_URL", &c.Jira.URL)
+	r.str("JIRA_USERNAME", &c.Jira.Username)
+	r.secret("JIRA_API_TOKEN", &c.Jira.APIToken, c.Jira.APITokenFile, c.Jira.APITokenCommand)
+	r.str("JIRA_PROJECT", &c.Jira.Project)
+	r.str("JIRA_STATUS", &c.Jira.Status)
+	r.str("JIRA_ASSIGNEE", &c.Jira.Assignee)
+	r.bool("JIRA_CURRENT_SPRINT", &c.Jira.CurrentSprint)
+	r.bool("JIRA_FETCH_COMMENTS", &c.Jira.FetchComments)
+	r.bool("JIRA_FETCH_WORKLOG", &c.Jira.FetchWorklog)
+	r.int("JIRA_COMMENTS_MAX", &c.Jira.CommentsMax)
+	r.int("JIRA_COMMENTS_MAX_CHARS", &c.Jira.CommentsChars)
+
+	r.str("SYNC_INTERVAL_MINUTES", &c.Sync.Interval)
+	r.int("CONFIG_WATCH_INTERVAL_SECONDS", &c.Sync.WatchIntervalSeconds)
+	r.int("RECONCILE_INTERVAL_MINUTES", &c.Sync.ReconcileIntervalMinutes)
+
+	r.str("STORAGE_BASE_PATH", &c.Storage.BasePath)
+
+	r.secret("DISCORD_BOT_TOKEN", &c.Discord.BotToken, c.Discord.BotTokenFile, c.Discord.BotTokenCommand)
+	r.str("DISCORD_GUILD_ID", &c.Discord.GuildID)
+	r.accountMap("DISCORD_CHANNELS", &c.Discord.Channels)
+	r.str("DISCORD_ROUTES_FILE", &c.Discord.RoutesFile)
+	r.int("DISCORD_RENOTIFY_INTERVAL_MINUTES", &c.Discord.RenotifyIntervalMinutes)
+	r.lower("DISCORD_REMINDER_MODE", &c.Discord.ReminderMode)
+	r.int("DISCORD_REMINDER_MAX", &c.Discord.ReminderMax)
+	r.int("DISCORD_REMINDER_BELOW_SCORE", &c.Discord.ReminderBelowScore)
+	r.str("DISCORD_QUIET_HOURS", &c.Discord.QuietHours)
+	r.bool("DISCORD_HANDOVER_NOTICE", &c.Discord.HandoverNotice)
+	r.str("DISCORD_FALLBACK_CHANNEL", &c.Discord.FallbackChannel)
+	r.accountMap("DISCORD_USERS", &c.Discord.Users)
+	r.lower("DISCORD_UNMAPPED_POLICY", &c.Discord.UnmappedPolicy)
+
+	r.lower("DB_DRIVER", &c.Database.Driver)
+	r.str("DB_PATH", &c.Database.Path)
+	r.str("DB_SSLMODE", &c.Database.SSLMode)
+	r.str("DB_HOST", &c.Database.Host)
+	r.str("DB_PORT", &c.Database.Port)
+	r.str("DB_USERNAME", &c.Database.Username)
+	r.secret("DB_PASSWORD", &c.Database.Password, c.Database.PasswordFile, c.Database.PasswordCommand)
+	r.str("DB_DATABASE", &c.Database.Database)
+
+	r.bool("EVAL_ENABLED", &c.Eval.Enabled)
+	r.secret("GEMINI_API_KEY", &c.Eval.APIKey, c.Eval.APIKeyFile, c.Eval.APIKeyCommand)
+	r.str("EVAL_MODEL", &c.Eval.Model)
+	r.str("EVAL_PROMPT_PHASE1", &c.Eval.PromptPhase1)
+	r.str("EVAL_PROMPT_PHASE2", &c.Eval.PromptPhase2)
+	r.lower("EVAL_REEVAL_ON_PROMPT_CHANGE", &c.Eval.ReevalPolicy)
+	r.int("EVAL_REEVAL_MAX_PER_TICK", &c.Eval.ReevalMaxPerTick)
+	r.str("EVAL_PIPELINE_FILE", &c.Eval.PipelineFile)
+	r.str("EVAL_PROMPT_SETS_DIR", &c.Eval.PromptSetsDir)
+	r.int("EVAL_SCORE_THRESHOLD", &c.Eval.ScoreThreshold)
+	r.bool("EVAL_HASH_COMMENTS", &c.Eval.HashComments)
+	if value := os.Getenv("EVAL_PROMPT_ROUTES"); value != "" {
+		routes, err := parsePromptRoutes(value)
+		if err != nil {
+			r.errs = append(r.errs, fmt.Errorf("EVAL_PROMPT_ROUTES: %v", err))
+		}
+		c.Eval.Routes = routes
+	}
+
+	r.int("LEADER_LEASE_SECONDS", &c.Leader.LeaseSeconds)
+	r.str("LEADER_ID", &c.Leader.ID)
+
+	r.str("METRICS_ADDR", &c.Metrics.Addr)
+
+	r.str("SCHEDULE_TIMEZONE", &c.Schedule.Timezone)
+	r.lower("SCHEDULE_WORK_DAYS", &c.Schedule.WorkDays)
+	r.str("SCHEDULE_WORK_HOURS", &c.Schedule.WorkHours)
+	r.str("SCHEDULE_HOLIDAYS_FILE", &c.Schedule.HolidaysFile)
+
+	return r.errs
+}
+
+// parseAccountMap parsea un mapa accountId -> ID de Discord
+// Formato esperado: DISCORD_CHANNELS="accountId1:channelID1,accountId2:channelID2"
+// Los accountId de Jira pueden contener ':' (p. ej. "557058:f58131cb-..."), por
+// eso se separa por el último ':'. Por compatibilidad también se aceptan nombres
+// visibles como clave.
+func parseAccountMap(value string) (map[string]string, error) {
+	channels := make(map[string]string)
+
+	var invalid []string
+	// Dividir por comas para obtener cada asignación
+	for _, pair := range strings.Split(value, ",") {
+		// Dividir cada par por el último ':'
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		idx := strings.LastIndex(pair, ":")
+		if idx < 0 {
+			invalid = append(invalid, pair)
+			continue
+		}
+		assignee := strings.TrimSpace(pair[:idx])
+		channelID := strings.TrimSpace(pair[idx+1:])
+		if assignee == "" || channelID == "" {
+			invalid = append(invalid, pair)
+			continue
+		}
+		channels[assignee] = channelID
+	}
+
+	if len(invalid) > 0 {
+		return channels, fmt.Errorf("entradas sin formato clave:id: %s", strings.Join(invalid, ", "))
+	}
+	return channels, nil
+}
+
+// parsePromptRoutes parsea la tabla de ruteo de prompts
+// Formato esperado: EVAL_PROMPT_ROUTES="BUG#Pagos=pagos,BUG=bug:70,SOPORTE@OPS=soporte"
+// es decir tipo[@proyecto][#componente]=set[:umbral]
+func parsePromptRoutes(value string) ([]PromptRoute, error) {
+	var routes []PromptRoute
+
+	var invalid []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		match, target, ok := strings.Cut(entry, "=")
+		if !ok {
+			invalid = append(invalid, entry)
+			continue
+		}
+
+		match, component, _ := strings.Cut(match, "#")
+		issueType, project, _ := strings.Cut(match, "@")
+		set, threshold, _ := strings.Cut(target, ":")
+		route := PromptRoute{
+			IssueType: strings.TrimSpace(issueType),
+			Project:   strings.TrimSpace(project),
+			Component: strings.TrimSpace(component),
+			Set:       strings.TrimSpace(set),
+		}
+		if threshold != "" {
+			parsed, err := strconv.Atoi(strings.TrimSpace(threshold))
+			if err != nil {
+				invalid = append(invalid, entry)
+				continue
+			}
+			route.Threshold = parsed
+		}
+		if route.IssueType == "" || route.Set == "" {
+			invalid = append(invalid, entry)
+			continue
+		}
+		routes = append(routes, route)
+	}
+
+	if len(invalid) > 0 {
+		return routes, fmt.Errorf("entradas sin formato tipo[@proyecto][#componente]=set[:umbral]: %s", strings.Join(invalid, ", "))
+	}
+	return routes, nil
+}
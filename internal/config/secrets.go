@@ -0,0 +1,180 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SecretSource obtiene el valor de un secreto a partir de una referencia (una
+// ruta, un comando, etc.). Cada fuente se asocia a un sufijo de variable de
+// entorno: con el sufijo "_FILE", JIRA_API_TOKEN_FILE es la referencia para
+// JIRA_API_TOKEN.
+type SecretSource interface {
+	Resolve(ref string) (string, error)
+}
+
+// FileSource lee el secreto de un archivo, como los secrets de Docker
+// (/run/secrets/...) o los volúmenes de secrets de Kubernetes
+type FileSource struct{}
+
+// Resolve lee el archivo y quita los espacios y saltos de línea de los extremos
+func (FileSource) Resolve(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error leyendo archivo de secreto: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// ExecSource ejecuta un comando y usa su salida estándar como secreto, p. ej.
+// "vault kv get -field=token secret/furina" o "op read op://infra/jira/token"
+type ExecSource struct {
+	Timeout time.Duration
+}
+
+// Resolve ejecuta el comando con el shell del sistema (cmd en Windows, sh en el resto)
+func (s ExecSource) Resolve(command string) (string, error) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// stderr puede ayudar a diagnosticar; stdout no se muestra porque podría contener el secreto
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("el comando de secreto falló: %v: %s", err, msg)
+		}
+		return "", fmt.Errorf("el comando de secreto falló: %v", err)
+	}
+
+	secret := strings.TrimSpace(stdout.String())
+	if secret == "" {
+		return "", fmt.Errorf("el comando de secreto no devolvió nada")
+	}
+	return secret, nil
+}
+
+// cachedSource recuerda lo que devolvió la fuente para cada referencia, así una
+// recarga de la configuración no vuelve a ejecutar el mismo comando. Cambiar el
+// comando en .env o en el archivo lo vuelve a ejecutar.
+type cachedSource struct {
+	source SecretSource
+
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// Resolve devuelve el valor ya obtenido para ref o lo pide a la fuente. Los errores no se guardan.
+func (s *cachedSource) Resolve(ref string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value, ok := s.values[ref]; ok {
+		return value, nil
+	}
+	value, err := s.source.Resolve(ref)
+	if err != nil {
+		return "", err
+	}
+	if s.values == nil {
+		s.values = make(map[string]string)
+	}
+	s.values[ref] = value
+	return value, nil
+}
+
+// secretVariant asocia un sufijo de variable de entorno a una fuente de secretos
+type secretVariant struct {
+	suffix string
+	source SecretSource
+}
+
+// secretSources son las fuentes disponibles, en el orden en que se revisan.
+// Los archivos se releen en cada recarga; los comandos se ejecutan una vez por
+// proceso (para rotar el secreto hay que reiniciar o cambiar el comando).
+var secretSources = []secretVariant{
+	{suffix: "_FILE", source: fileSource},
+	{suffix: "_COMMAND", source: commandSource},
+}
+
+// Fuentes de _FILE y _COMMAND; también resuelven las referencias del archivo YAML
+var (
+	fileSource    SecretSource = FileSource{}
+	commandSource SecretSource = &cachedSource{source: ExecSource{}}
+)
+
+// RegisterSecretSource agrega una fuente de secretos para las variables con el
+// sufijo indicado (p. ej. "_VAULT"). Debe llamarse antes de Load.
+func RegisterSecretSource(suffix string, source SecretSource) {
+	secretSources = append(secretSources, secretVariant{suffix: strings.ToUpper(suffix), source: source})
+}
+
+// secret lee un secreto de la variable de entorno o de una de sus variantes
+// (JIRA_API_TOKEN_FILE, JIRA_API_TOKEN_COMMAND, ...). Definir más de una es un
+// error, para que no haya dudas sobre cuál se usa. Si el entorno no define
+// ninguna se usan las referencias del archivo de configuración (file y command,
+// p. ej. jira.api_token_file), con las mismas fuentes que _FILE y _COMMAND.
+func (r *envReader) secret(key string, dst *string, file, command string) {
+	var defined []string
+	if os.Getenv(key) != "" {
+		defined = append(defined, key)
+	}
+	var variant *secretVariant
+	for i := range secretSources {
+		if os.Getenv(key+secretSources[i].suffix) != "" {
+			defined = append(defined, key+secretSources[i].suffix)
+			variant = &secretSources[i]
+		}
+	}
+	if len(defined) > 1 {
+		r.errs = append(r.errs, fmt.Errorf("%s: definir solo una de %s", key, strings.Join(defined, ", ")))
+		return
+	}
+
+	if len(defined) == 0 {
+		switch {
+		case file != "" && command != "":
+			r.errs = append(r.errs, fmt.Errorf("%s: definir en el archivo solo una referencia, file o command", key))
+			return
+		case file != "":
+			r.resolveSecret(key+" (archivo de configuración)", fileSource, file, dst)
+			return
+		case command != "":
+			r.resolveSecret(key+" (archivo de configuración)", commandSource, command, dst)
+			return
+		}
+	}
+	if variant == nil {
+		r.str(key, dst)
+		return
+	}
+	name := key + variant.suffix
+	r.resolveSecret(name, variant.source, os.Getenv(name), dst)
+}
+
+// resolveSecret pide el secreto a la fuente y registra el error con el nombre de su origen
+func (r *envReader) resolveSecret(name string, source SecretSource, ref string, dst *string) {
+	value, err := source.Resolve(ref)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s: %v", name, err))
+		return
+	}
+	*dst = value
+}
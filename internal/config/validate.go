@@ -0,0 +1,219 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted reemplaza a los secretos en la salida de config check
+const redacted = "********"
+
+// validate revisa campos obligatorios, formatos y rangos. Devuelve todos los
+// problemas encontrados, no solo el primero. Los horarios (zona horaria,
+// horario laboral, expresiones cron, DISCORD_QUIET_HOURS) se interpretan al
+// armarlos en main, que reporta sus errores de la misma forma.
+func (c *Config) validate() []error {
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	required := func(name, value string) {
+		if value == "" {
+			add("%s es obligatorio", name)
+		}
+	}
+	between := func(name string, value, min, max int) {
+		if value < min || value > max {
+			add("%s debe estar entre %d y %d (valor: %d)", name, min, max, value)
+		}
+	}
+	atLeast := func(name string, value, min int) {
+		if value < min {
+			add("%s debe ser mayor o igual a %d (valor: %d)", name, min, value)
+		}
+	}
+	snowflake := func(name, value string) {
+		if _, err := strconv.ParseUint(value, 10, 64); value != "" && err != nil {
+			add("%s: %q no es un ID numérico de Discord", name, value)
+		}
+	}
+
+	// Jira
+	required("JIRA_URL", c.Jira.URL)
+	if c.Jira.URL != "" {
+		if u, err := url.Parse(c.Jira.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("JIRA_URL: %q no es una URL http(s) válida", c.Jira.URL)
+		}
+	}
+	required("JIRA_USERNAME", c.Jira.Username)
+	required("JIRA_API_TOKEN", c.Jira.APIToken)
+	atLeast("JIRA_COMMENTS_MAX", c.Jira.CommentsMax, 0)
+	atLeast("JIRA_COMMENTS_MAX_CHARS", c.Jira.CommentsChars, 0)
+
+	// Sincronización
+	required("SYNC_INTERVAL_MINUTES", c.Sync.Interval)
+	atLeast("CONFIG_WATCH_INTERVAL_SECONDS", c.Sync.WatchIntervalSeconds, 0)
+	atLeast("RECONCILE_INTERVAL_MINUTES", c.Sync.ReconcileIntervalMinutes, 0)
+	required("STORAGE_BASE_PATH", c.Storage.BasePath)
+
+	// Discord
+	required("DISCORD_BOT_TOKEN", c.Discord.BotToken)
+	for assignee, channelID := range c.Discord.Channels {
+		snowflake("DISCORD_CHANNELS["+assignee+"]", channelID)
+	}
+	for assignee, userID := range c.Discord.Users {
+		snowflake("DISCORD_USERS["+assignee+"]", userID)
+	}
+	snowflake("DISCORD_FALLBACK_CHANNEL", c.Discord.FallbackChannel)
+	if len(c.Discord.Channels) == 0 && c.Discord.RoutesFile == "" && c.Discord.FallbackChannel == "" {
+		add("no hay ningún destino configurado: DISCORD_CHANNELS, DISCORD_ROUTES_FILE o DISCORD_FALLBACK_CHANNEL")
+	}
+	atLeast("DISCORD_RENOTIFY_INTERVAL_MINUTES", c.Discord.RenotifyIntervalMinutes, 0)
+	switch c.Discord.ReminderMode {
+	case ReminderReply, ReminderRepost:
+	default:
+		add("DISCORD_REMINDER_MODE inválido: %q (valores: reply, repost)", c.Discord.ReminderMode)
+	}
+	atLeast("DISCORD_REMINDER_MAX", c.Discord.ReminderMax, 0)
+	between("DISCORD_REMINDER_BELOW_SCORE", c.Discord.ReminderBelowScore, 0, 100)
+	switch c.Discord.UnmappedPolicy {
+	case UnmappedDM, UnmappedFallback, UnmappedSkip:
+	default:
+		add("DISCORD_UNMAPPED_POLICY inválido: %q (valores: dm, fallback, skip)", c.Discord.UnmappedPolicy)
+	}
+
+	// Base de datos
+	errs = append(errs, c.validateDatabase()...)
+
+	// Evaluador
+	if !c.Eval.Enabled {
+		add("EVAL_ENABLED debe estar en true: el evaluador IA es requerido")
+	}
+	required("GEMINI_API_KEY", c.Eval.APIKey)
+	required("EVAL_MODEL", c.Eval.Model)
+	required("EVAL_PROMPT_PHASE1", c.Eval.PromptPhase1)
+	switch c.Eval.ReevalPolicy {
+	case ReevalAll, ReevalGradual, ReevalNone:
+	default:
+		add("EVAL_REEVAL_ON_PROMPT_CHANGE inválido: %q (valores: all, gradual, none)", c.Eval.ReevalPolicy)
+	}
+	atLeast("EVAL_REEVAL_MAX_PER_TICK", c.Eval.ReevalMaxPerTick, 0)
+	between("EVAL_SCORE_THRESHOLD", c.Eval.ScoreThreshold, 0, 100)
+	for _, route := range c.Eval.Routes {
+		if route.IssueType == "" || route.Set == "" {
+			add("EVAL_PROMPT_ROUTES: cada ruta necesita tipo y set (%+v)", route)
+		}
+		between("umbral de la ruta "+route.IssueType+"="+route.Set, route.Threshold, 0, 100)
+	}
+
+	// Elección de líder: el heartbeat es cada lease/3
+	if c.Leader.LeaseSeconds != 0 && c.Leader.LeaseSeconds < 6 {
+		add("LEADER_LEASE_SECONDS debe ser 0 (sin elección) o al menos 6 (valor: %d)", c.Leader.LeaseSeconds)
+	}
+
+	// Resúmenes
+	names := make(map[string]bool)
+	for i, digest := range c.Report.Digests {
+		label := fmt.Sprintf("report.digests[%d]", i)
+		if digest.Name != "" {
+			label = "resumen " + digest.Name
+		}
+		switch {
+		case digest.Name == "":
+			add("%s: name es obligatorio", label)
+		case names[digest.Name]:
+			add("%s: nombre repetido", label)
+		}
+		names[digest.Name] = true
+		switch digest.Type {
+		case DigestSummary, DigestLeaderboard:
+		default:
+			add("%s: type inválido: %q (valores: summary, leaderboard)", label, digest.Type)
+		}
+		required(label+": channel", digest.Channel)
+		snowflake(label+": channel", digest.Channel)
+		switch digest.Period {
+		case DigestDaily, DigestWeekly:
+		default:
+			add("%s: period inválido: %q (valores: daily, weekly)", label, digest.Period)
+		}
+		if _, ok := c.Report.Teams[digest.Team]; digest.Team != "" && !ok {
+			add("%s: el equipo %q no está en report.teams", label, digest.Team)
+		}
+		atLeast(label+": worst", digest.Worst, 1)
+		between(label+": percentile", digest.Percentile, 1, 100)
+		between(label+": periods", digest.Periods, 2, 52)
+	}
+	for team, members := range c.Report.Teams {
+		if len(members) == 0 {
+			add("report.teams[%s] no tiene integrantes", team)
+		}
+	}
+
+	if c.Metrics.Addr != "" {
+		if _, port, err := net.SplitHostPort(c.Metrics.Addr); err != nil || port == "" {
+			add("METRICS_ADDR: %q no es una dirección host:puerto (p. ej. :9090)", c.Metrics.Addr)
+		}
+	}
+
+	return errs
+}
+
+// validateDatabase revisa la configuración de la base de datos; es lo único que
+// se valida en LoadDatabase
+func (c *Config) validateDatabase() []error {
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	required := func(name, value string) {
+		if value == "" {
+			add("%s es obligatorio", name)
+		}
+	}
+
+	switch c.Database.Driver {
+	case DriverMySQL, DriverPostgres:
+		required("DB_HOST", c.Database.Host)
+		if port, err := strconv.Atoi(c.Database.Port); err != nil || port < 1 || port > 65535 {
+			add("DB_PORT: %q no es un puerto válido (1-65535)", c.Database.Port)
+		}
+		required("DB_USERNAME", c.Database.Username)
+		required("DB_DATABASE", c.Database.Database)
+	case DriverSQLite:
+		required("DB_PATH", c.Database.Path)
+	default:
+		add("DB_DRIVER inválido: %q (valores: mysql, sqlite, postgres)", c.Database.Driver)
+	}
+	switch c.Database.SSLMode {
+	case "", "disable", "require", "verify-ca", "verify-full":
+	default:
+		add("DB_SSLMODE inválido: %q (valores: disable, require, verify-ca, verify-full)", c.Database.SSLMode)
+	}
+	return errs
+}
+
+// Redacted devuelve una copia de la configuración con los secretos ocultos
+func (c *Config) Redacted() *Config {
+	copy := *c
+	hide := func(value *string) {
+		if *value != "" {
+			*value = redacted
+		}
+	}
+	hide(&copy.Jira.APIToken)
+	hide(&copy.Discord.BotToken)
+	hide(&copy.Database.Password)
+	hide(&copy.Eval.APIKey)
+	return &copy
+}
+
+// YAML serializa la configuración en el mismo formato que el archivo de configuración
+func (c *Config) YAML() (string, error) {
+	data, err := yaml.Marshal(c)
+	return string(data), err
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// dialect reúne lo que cambia entre motores de base de datos. Las consultas
+// se escriben con '?' como marcador de parámetros y se traducen con rebind.
+type dialect struct {
+	name            string // también es el directorio de sus migraciones
+	migrationsTable string // crea schema_migrations
+	columnQuery     string // (tabla, columna) → si admite NULL; sin filas si no existe
+	indexQuery      string // (tabla, índice) → cantidad de índices con ese nombre
+
+	// lock y unlock toman y sueltan el bloqueo de migraciones en una conexión
+	// dedicada, para que dos instancias que arrancan a la vez no migren las
+	// dos. nil = sin bloqueo.
+	lock   func(ctx context.Context, conn *sql.Conn) error
+	unlock func(ctx context.Context, conn *sql.Conn) error
+
+	// upsertClause completa un INSERT para que actualice updates cuando ya
+	// existe una fila con la misma clave conflict
+	upsertClause func(conflict, updates []string) string
+
+	// placeholder devuelve el marcador del parámetro n (desde 1); nil = '?'
+	placeholder func(n int) string
+}
+
+// rebind reemplaza los '?' de la consulta por los marcadores del motor. Las
+// consultas del paquete no tienen '?' dentro de literales.
+func (d *dialect) rebind(query string) string {
+	if d.placeholder == nil {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, ch := range query {
+		if ch == '?' {
+			n++
+			b.WriteString(d.placeholder(n))
+			continue
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
+
+// upsert genera un INSERT de columns en table que actualiza updates si la fila ya existe
+func (d *dialect) upsert(table string, columns, conflict, updates []string) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) %s",
+		table, strings.Join(columns, ", "), placeholders, d.upsertClause(conflict, updates))
+}
+
+// onConflictUpdate es la sintaxis de upsert de SQLite y PostgreSQL
+func onConflictUpdate(conflict, updates []string) string {
+	sets := make([]string, len(updates))
+	for i, column := range updates {
+		sets[i] = fmt.Sprintf("%s = excluded.%s", column, column)
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(conflict, ", "), strings.Join(sets, ", "))
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// HistoryEntry es una evaluación registrada en evaluation_history (migración
+// 0005). A diferencia de incident_evaluations, que guarda solo la última de cada
+// incidencia, el historial guarda todas con el assignee y el título que tenía
+// la incidencia al evaluarla.
+type HistoryEntry struct {
+	IncidentKey   string
+	Title         string
+	AssigneeID    string
+	Assignee      string
+	PromptSet     string
+	PromptVersion string
+	PhaseResults  string // JSON de las fases (ver evaluator.EvaluationResult.PhasesJSON)
+	EvaluatedAt   time.Time
+}
+
+// DigestRun es un resumen publicado (ver internal/report)
+type DigestRun struct {
+	Name        string
+	ChannelID   string
+	MessageID   string // vacío hasta que el outbox lo envía
+	Ref         string // operación del outbox que lo envía
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Evaluated   int // incidencias evaluadas en el período
+}
+
+// maxTitle es el largo máximo del título guardado en el historial
+const maxTitle = 500
+
+// Las horas del historial y de los resúmenes se escriben y se comparan en UTC,
+// como las del lease: SQLite las guarda como texto con la zona horaria y las
+// compara como texto, así que con zonas distintas el orden no sería el real.
+
+func (c *Client) insertHistory(ex execer, entry HistoryEntry) error {
+	if entry.EvaluatedAt.IsZero() {
+		entry.EvaluatedAt = time.Now()
+	}
+	if title := []rune(entry.Title); len(title) > maxTitle {
+		entry.Title = string(title[:maxTitle])
+	}
+	_, err := c.execOn(ex, `INSERT INTO evaluation_history (incident_key, title, assignee_id, assignee, prompt_set, prompt_version, phase_results, evaluated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.IncidentKey, entry.Title, entry.AssigneeID, entry.Assignee, entry.PromptSet, entry.PromptVersion, entry.PhaseResults, entry.EvaluatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("error guardando historial de %s: %v", entry.IncidentKey, err)
+	}
+	return nil
+}
+
+// EvaluationHistory devuelve las evaluaciones hechas en [from, to), de la más
+// antigua a la más nueva
+func (c *Client) EvaluationHistory(from, to time.Time) ([]HistoryEntry, error) {
+	rows, err := c.query(`SELECT incident_key, title, assignee_id, assignee, prompt_set, prompt_version, phase_results, evaluated_at
+	FROM evaluation_history WHERE evaluated_at >= ? AND evaluated_at < ? ORDER BY evaluated_at, id`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("error consultando historial de evaluaciones: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []HistoryEntry
+	for rows.Next() {
+		var e HistoryEntry
+		if err := rows.Scan(&e.IncidentKey, &e.Title, &e.AssigneeID, &e.Assignee, &e.PromptSet, &e.PromptVersion,
+			&e.PhaseResults, &e.EvaluatedAt); err != nil {
+			return nil, fmt.Errorf("error escaneando historial de evaluaciones: %v", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// LastDigestRun devuelve el último resumen registrado con ese nombre (enviado o
+// pendiente en el outbox), o nil si nunca se publicó
+func (c *Client) LastDigestRun(name string) (*DigestRun, error) {
+	run := DigestRun{Name: name}
+	err := c.queryRow(`SELECT channel_id, message_id, ref, period_start, period_end, evaluated
+	FROM digest_runs WHERE name = ? ORDER BY period_end DESC LIMIT 1`, name).
+		Scan(&run.ChannelID, &run.MessageID, &run.Ref, &run.PeriodStart, &run.PeriodEnd, &run.Evaluated)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando último resumen %s: %v", name, err)
+	}
+	return &run, nil
+}
+
+// EnqueueDigest registra el resumen y la operación del outbox que lo publica
+// en una transacción: desde ese momento el período cuenta como publicado y el
+// envío lo reintenta el outbox. Con lease solo se registra si la instancia
+// sigue siendo líder; si no, devuelve ErrLeaseLost. entry.Ref queda en run.Ref.
+func (c *Client) EnqueueDigest(run DigestRun, entry OutboxEntry, lease Lease) error {
+	if entry.Ref == "" {
+		entry.Ref = NewOutboxRef()
+	}
+	return c.inTx(func(tx *sql.Tx) error {
+		if err := c.checkLease(tx, lease); err != nil {
+			return err
+		}
+		_, err := c.execOn(tx, `INSERT INTO digest_runs (name, channel_id, message_id, ref, period_start, period_end, evaluated, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			run.Name, run.ChannelID, "", entry.Ref, run.PeriodStart.UTC(), run.PeriodEnd.UTC(), run.Evaluated, time.Now().UTC())
+		if err != nil {
+			return fmt.Errorf("error registrando resumen %s: %v", run.Name, err)
+		}
+		return c.enqueue(tx, []OutboxEntry{entry})
+	})
+}
+
+// DigestStart devuelve desde cuándo existe el resumen name: la primera vez que
+// se consulta registra now. Un resumen que nunca se publicó cuenta su primer
+// horario desde ahí, aunque el proceso se reinicie.
+func (c *Client) DigestStart(name string, now time.Time) (time.Time, error) {
+	// Si otra instancia lo registró a la vez, el upsert conserva su hora
+	query := c.dialect.upsert("digest_schedules", []string{"name", "started_at"}, []string{"name"}, []string{"name"})
+	if _, err := c.exec(query, name, now.UTC()); err != nil {
+		return time.Time{}, fmt.Errorf("error registrando el inicio del resumen %s: %v", name, err)
+	}
+	var started time.Time
+	if err := c.queryRow(`SELECT started_at FROM digest_schedules WHERE name = ?`, name).Scan(&started); err != nil {
+		return time.Time{}, fmt.Errorf("error consultando el inicio del resumen %s: %v", name, err)
+	}
+	return started, nil
+}
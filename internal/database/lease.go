@@ -0,0 +1,93 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Los leases se guardan en leader_lease (migración 0002). Las horas se escriben
+// en UTC desde la aplicación para que todos los motores comparen igual; las
+// instancias deben tener los relojes sincronizados con un margen bastante
+// menor que el ttl.
+
+// Lease identifica el lease con que se escriben los resultados del outbox: solo
+// se registran si holder sigue siendo el dueño. El valor cero no verifica nada
+// (una sola instancia, sin elección de líder).
+type Lease struct {
+	Name   string
+	Holder string
+}
+
+// ErrLeaseLost indica que el resultado no se registró porque otra instancia
+// tomó el lease
+var ErrLeaseLost = errors.New("esta instancia ya no tiene el lease de líder")
+
+// fence agrega a un UPDATE la condición de que holder siga teniendo el lease
+func (l Lease) fence(query string, args []interface{}) (string, []interface{}) {
+	if l.Name == "" {
+		return query, args
+	}
+	return query + ` AND EXISTS (SELECT 1 FROM leader_lease WHERE name = ? AND holder = ?)`, append(args, l.Name, l.Holder)
+}
+
+// checkLease devuelve ErrLeaseLost si holder ya no tiene el lease. Sirve para
+// los INSERT, que no admiten fence; va dentro de la transacción que escribe.
+func (c *Client) checkLease(tx *sql.Tx, lease Lease) error {
+	if lease.Name == "" {
+		return nil
+	}
+	var holder string
+	if err := tx.QueryRow(c.dialect.rebind(`SELECT holder FROM leader_lease WHERE name = ?`), lease.Name).Scan(&holder); err != nil {
+		return fmt.Errorf("error consultando lease %s: %v", lease.Name, err)
+	}
+	if holder != lease.Holder {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// AcquireLease toma o renueva el lease name para holder durante ttl. Devuelve
+// true si holder queda como dueño: ya lo era, o el lease estaba vencido. El
+// UPDATE es atómico, así que si dos instancias lo intentan a la vez solo una
+// lo consigue.
+func (c *Client) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	if _, err := c.exec(`UPDATE leader_lease SET holder = ?, expires_at = ? WHERE name = ? AND (holder = ? OR expires_at < ?)`,
+		holder, now.Add(ttl), name, holder, now); err != nil {
+		return false, fmt.Errorf("error renovando lease %s: %v", name, err)
+	}
+
+	// Se confirma leyendo la fila: en MySQL un UPDATE que no cambia valores no
+	// cuenta como fila afectada
+	current, _, err := c.LeaseHolder(name)
+	if err != nil {
+		return false, err
+	}
+	return current == holder, nil
+}
+
+// ReleaseLease vence el lease si holder es el dueño, para que otra instancia lo
+// tome sin esperar al ttl
+func (c *Client) ReleaseLease(name, holder string) error {
+	expired := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := c.exec(`UPDATE leader_lease SET expires_at = ? WHERE name = ? AND holder = ?`, expired, name, holder); err != nil {
+		return fmt.Errorf("error liberando lease %s: %v", name, err)
+	}
+	return nil
+}
+
+// LeaseHolder devuelve el dueño actual del lease y hasta cuándo lo tiene
+func (c *Client) LeaseHolder(name string) (string, time.Time, error) {
+	var holder string
+	var expiresAt time.Time
+	err := c.queryRow(`SELECT holder, expires_at FROM leader_lease WHERE name = ?`, name).Scan(&holder, &expiresAt)
+	if err == sql.ErrNoRows {
+		return "", time.Time{}, fmt.Errorf("el lease %s no existe (¿faltan migraciones?)", name)
+	}
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("error consultando lease %s: %v", name, err)
+	}
+	return holder, expiresAt, nil
+}
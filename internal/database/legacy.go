@@ -0,0 +1,167 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Formato de phase_results (ver evaluator.PhaseResult). Se repite aquí para
+// convertir las filas antiguas sin que el paquete dependa del evaluador.
+type legacyPhase struct {
+	Name    string         `json:"name"`
+	Label   string         `json:"label"`
+	Score   int            `json:"score"`
+	Notes   string         `json:"notes"`
+	Outputs []legacyOutput `json:"outputs"`
+	Skipped string         `json:"skipped,omitempty"`
+}
+
+type legacyOutput struct {
+	Key   string      `json:"key"`
+	Label string      `json:"label"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// phase1Result y phase2Result son las columnas de las versiones con dos fases fijas
+type phase1Result struct {
+	Claridad        string `json:"claridad"`
+	CausaRaiz       string `json:"causa_raiz"`
+	ImpactoDefinido bool   `json:"impacto_definido"`
+	Puntaje         int    `json:"puntaje"`
+	Observaciones   string `json:"observaciones"`
+}
+
+type phase2Result struct {
+	CoherenciaConDesc bool   `json:"coherencia_con_descripcion"`
+	AccionesDefinidas bool   `json:"acciones_definidas"`
+	ResponsablesAsig  bool   `json:"responsables_asignados"`
+	Puntaje           int    `json:"puntaje"`
+	Observaciones     string `json:"observaciones"`
+}
+
+// convertLegacyPhases arma phase_results a partir de phase1_result y
+// phase2_result con las fases del pipeline por defecto (descripción y
+// conclusión), así la caché y el historial las leen como cualquier otra
+func convertLegacyPhases(phase1, phase2 string) (string, error) {
+	var p1 phase1Result
+	if err := json.Unmarshal([]byte(phase1), &p1); err != nil {
+		return "", fmt.Errorf("phase1_result ilegible: %v", err)
+	}
+	phases := []legacyPhase{{
+		Name:  "descripcion",
+		Label: "Descripción",
+		Score: p1.Puntaje,
+		Notes: p1.Observaciones,
+		Outputs: []legacyOutput{
+			{Key: "claridad", Label: "Claridad", Type: "string", Value: p1.Claridad},
+			{Key: "causa_raiz", Label: "Causa raíz", Type: "string", Value: p1.CausaRaiz},
+			{Key: "impacto_definido", Label: "Impacto", Type: "bool", Value: p1.ImpactoDefinido},
+		},
+	}}
+
+	conclusion := legacyPhase{Name: "conclusion", Label: "Conclusión", Skipped: "Sin conclusión — evaluación pendiente"}
+	if phase2 != "" && phase2 != "null" {
+		var p2 phase2Result
+		if err := json.Unmarshal([]byte(phase2), &p2); err != nil {
+			return "", fmt.Errorf("phase2_result ilegible: %v", err)
+		}
+		conclusion = legacyPhase{
+			Name:  "conclusion",
+			Label: "Conclusión",
+			Score: p2.Puntaje,
+			Notes: p2.Observaciones,
+			Outputs: []legacyOutput{
+				{Key: "coherencia_con_descripcion", Label: "Coherencia", Type: "bool", Value: p2.CoherenciaConDesc},
+				{Key: "acciones_definidas", Label: "Acciones", Type: "bool", Value: p2.AccionesDefinidas},
+				{Key: "responsables_asignados", Label: "Responsables", Type: "bool", Value: p2.ResponsablesAsig},
+			},
+		}
+	}
+	phases = append(phases, conclusion)
+
+	data, err := json.Marshal(phases)
+	return string(data), err
+}
+
+// backfillPhaseResults es la migración 0006: completa phase_results en las
+// evaluaciones guardadas antes del pipeline de fases, para que la caché las
+// siga usando en lugar de volver a evaluarlas, y las agrega al historial. Es
+// idempotente: solo toca las filas sin phase_results, y no hace nada en las
+// bases que nunca tuvieron phase1_result.
+func (c *Client) backfillPhaseResults() error {
+	exists, _, err := c.columnInfo("incident_evaluations", "phase1_result")
+	if err != nil || !exists {
+		return err
+	}
+
+	type legacyRow struct {
+		key            string
+		phase1, phase2 string
+		evaluatedAt    time.Time
+	}
+	rows, err := c.query(`SELECT incident_key, phase1_result, phase2_result, evaluated_at
+	FROM incident_evaluations WHERE phase_results IS NULL AND phase1_result IS NOT NULL`)
+	if err != nil {
+		return fmt.Errorf("error consultando evaluaciones anteriores al pipeline: %v", err)
+	}
+	var legacy []legacyRow
+	for rows.Next() {
+		var row legacyRow
+		var phase2 sql.NullString
+		if err := rows.Scan(&row.key, &row.phase1, &phase2, &row.evaluatedAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("error escaneando evaluación anterior al pipeline: %v", err)
+		}
+		row.phase2 = phase2.String
+		legacy = append(legacy, row)
+	}
+	rows.Close()
+
+	converted := 0
+	for _, row := range legacy {
+		phases, err := convertLegacyPhases(row.phase1, row.phase2)
+		if err != nil {
+			// Sin phase_results la incidencia se vuelve a evaluar: es lo que pasaba antes
+			log.Printf("Advertencia: evaluación de %s no convertida: %v", row.key, err)
+			continue
+		}
+		err = c.inTx(func(tx *sql.Tx) error {
+			if _, err := c.execOn(tx, `UPDATE incident_evaluations SET phase_results = ? WHERE incident_key = ? AND phase_results IS NULL`,
+				phases, row.key); err != nil {
+				return fmt.Errorf("error convirtiendo evaluación de %s: %v", row.key, err)
+			}
+			// El historial se sembró sin estas filas (0005); el assignee sale de sus mensajes, como allí
+			var inHistory int
+			if err := tx.QueryRow(c.dialect.rebind(`SELECT COUNT(*) FROM evaluation_history WHERE incident_key = ?`), row.key).Scan(&inHistory); err != nil {
+				return fmt.Errorf("error consultando historial de %s: %v", row.key, err)
+			}
+			if inHistory > 0 {
+				return nil
+			}
+			var assigneeID, assignee sql.NullString
+			if err := tx.QueryRow(c.dialect.rebind(`SELECT MAX(assignee_id), MAX(assignee) FROM discord_messages WHERE incident_key = ?`), row.key).
+				Scan(&assigneeID, &assignee); err != nil {
+				return fmt.Errorf("error consultando assignee de %s: %v", row.key, err)
+			}
+			return c.insertHistory(tx, HistoryEntry{
+				IncidentKey:  row.key,
+				AssigneeID:   assigneeID.String,
+				Assignee:     assignee.String,
+				PhaseResults: phases,
+				EvaluatedAt:  row.evaluatedAt,
+			})
+		})
+		if err != nil {
+			return err
+		}
+		converted++
+	}
+	if converted > 0 {
+		log.Printf("Evaluaciones anteriores al pipeline convertidas a phase_results: %d", converted)
+	}
+	return nil
+}
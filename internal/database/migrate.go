@@ -0,0 +1,230 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Las migraciones son archivos NNNN_nombre.sql en migrations/<motor>/. Se
+// aplican en orden de versión y cada una una sola vez; las aplicadas quedan en
+// schema_migrations. Una migración ya publicada no se modifica: los cambios
+// van en una migración nueva.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationHooks son pasos en Go que se ejecutan después del SQL de una
+// versión, para lo que no se puede expresar en SQL portable
+var migrationHooks = map[int]func(c *Client) error{
+	1:  (*Client).upgradeLegacySchema,
+	4:  (*Client).addReminderCount,
+	6:  (*Client).backfillPhaseResults,
+	7:  (*Client).migrateAssigneeIDs,
+	8:  (*Client).migrateMessageChannels,
+	11: (*Client).addDigestRef,
+}
+
+// lockTimeout es cuánto se espera el bloqueo de otra instancia que está migrando
+const lockTimeout = 5 * time.Minute
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// MigrationState es el estado de una migración conocida o aplicada
+type MigrationState struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Unknown   bool // aplicada por una versión más nueva del bot; no está en este binario
+}
+
+// migrations lee las migraciones del motor, ordenadas por versión
+func (c *Client) migrations() ([]migration, error) {
+	dir := path.Join("migrations", c.dialect.name)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo migraciones de %s: %v", c.dialect.name, err)
+	}
+
+	var migrations []migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("nombre de migración inválido: %s (formato: NNNN_nombre.sql)", entry.Name())
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("versión de migración %d repetida: %s y %s", version, other, entry.Name())
+		}
+		seen[version] = entry.Name()
+
+		data, err := migrationFiles.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error leyendo migración %s: %v", entry.Name(), err)
+		}
+		migrations = append(migrations, migration{version: version, name: name, sql: string(data)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// applied devuelve las versiones registradas en schema_migrations
+func (c *Client) applied() (map[int]MigrationState, error) {
+	if _, err := c.exec(c.dialect.migrationsTable); err != nil {
+		return nil, fmt.Errorf("error creando tabla schema_migrations: %v", err)
+	}
+
+	rows, err := c.query(`SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando schema_migrations: %v", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int]MigrationState)
+	for rows.Next() {
+		state := MigrationState{Applied: true}
+		if err := rows.Scan(&state.Version, &state.Name, &state.AppliedAt); err != nil {
+			return nil, fmt.Errorf("error escaneando schema_migrations: %v", err)
+		}
+		result[state.Version] = state
+	}
+	return result, rows.Err()
+}
+
+// MigrationStatus lista las migraciones del binario y las aplicadas, por versión
+func (c *Client) MigrationStatus() ([]MigrationState, error) {
+	migrations, err := c.migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := c.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var states []MigrationState
+	for _, m := range migrations {
+		state, ok := applied[m.version]
+		if !ok {
+			state = MigrationState{Version: m.version, Name: m.name}
+		}
+		delete(applied, m.version)
+		states = append(states, state)
+	}
+	for _, state := range applied {
+		state.Unknown = true
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Version < states[j].Version })
+	return states, nil
+}
+
+// Migrate aplica las migraciones pendientes bajo el bloqueo del motor. Si otra
+// instancia está migrando, espera a que termine y después solo aplica lo que
+// siga pendiente.
+func (c *Client) Migrate() error {
+	migrations, err := c.migrations()
+	if err != nil {
+		return err
+	}
+
+	if c.dialect.lock != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
+		defer cancel()
+		conn, err := c.db.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("error obteniendo conexión para migrar: %v", err)
+		}
+		defer conn.Close()
+		if err := c.dialect.lock(ctx, conn); err != nil {
+			return fmt.Errorf("error tomando el bloqueo de migraciones: %v", err)
+		}
+		defer func() {
+			if err := c.dialect.unlock(context.Background(), conn); err != nil {
+				log.Printf("Advertencia: error liberando el bloqueo de migraciones: %v", err)
+			}
+		}()
+	}
+
+	// Se lee después de tomar el bloqueo para ver lo que aplicó otra instancia
+	applied, err := c.applied()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+		if err := c.apply(m); err != nil {
+			return err
+		}
+		log.Printf("Migración %04d aplicada: %s", m.version, m.name)
+	}
+	return nil
+}
+
+// apply ejecuta una migración y la registra. Las sentencias no van en una
+// transacción porque MySQL confirma cada DDL por separado; si una falla, la
+// migración queda sin registrar y se reintenta en el siguiente arranque, así
+// que conviene escribirlas idempotentes (IF NOT EXISTS).
+func (c *Client) apply(m migration) error {
+	for _, statement := range splitStatements(m.sql) {
+		if _, err := c.exec(statement); err != nil {
+			return fmt.Errorf("migración %04d_%s: %v", m.version, m.name, err)
+		}
+	}
+	if hook := migrationHooks[m.version]; hook != nil {
+		if err := hook(c); err != nil {
+			return fmt.Errorf("migración %04d_%s: %v", m.version, m.name, err)
+		}
+	}
+
+	if _, err := c.exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.version, m.name, time.Now()); err != nil {
+		return fmt.Errorf("error registrando migración %04d_%s: %v", m.version, m.name, err)
+	}
+	return nil
+}
+
+// splitStatements separa un archivo de migración en sentencias. Los drivers no
+// aceptan varias sentencias por llamada. Quita las líneas de comentario "--" y
+// separa por ';' al final de línea, así que las migraciones no deben tener ';'
+// al final de una línea dentro de un literal.
+func splitStatements(sql string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
@@ -0,0 +1,27 @@
+-- Esquema inicial. En bases creadas por versiones anteriores las tablas ya
+-- existen; las columnas que les faltan las agrega upgradeLegacySchema.
+
+CREATE TABLE IF NOT EXISTS discord_messages (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	incident_key VARCHAR(255) NOT NULL,
+	channel_id VARCHAR(255) NOT NULL,
+	message_id VARCHAR(255) NOT NULL,
+	assignee VARCHAR(255) NOT NULL,
+	assignee_id VARCHAR(128) NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_notification DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE KEY unique_incident_channel (incident_key, channel_id),
+	INDEX idx_incident_key (incident_key),
+	INDEX idx_channel_message (channel_id, message_id)
+);
+
+CREATE TABLE IF NOT EXISTS incident_evaluations (
+	incident_key    VARCHAR(50)  NOT NULL,
+	jira_updated_at DATETIME     NOT NULL,
+	phase_results   JSON         NOT NULL,
+	input_hash      CHAR(64)     NOT NULL DEFAULT '',
+	prompt_version  VARCHAR(100) NOT NULL DEFAULT '',
+	evaluated_at    DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (incident_key),
+	INDEX idx_updated (jira_updated_at)
+);
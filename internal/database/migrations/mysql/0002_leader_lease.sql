@@ -0,0 +1,10 @@
+-- Lease de líder: solo la instancia que lo tiene sincroniza (ver internal/leader).
+-- La fila se crea vencida para que la primera instancia la tome con un UPDATE.
+
+CREATE TABLE IF NOT EXISTS leader_lease (
+	name       VARCHAR(64)  NOT NULL PRIMARY KEY,
+	holder     VARCHAR(255) NOT NULL DEFAULT '',
+	expires_at DATETIME(3)  NOT NULL
+);
+
+INSERT IGNORE INTO leader_lease (name, holder, expires_at) VALUES ('sync', '', '2000-01-01 00:00:00');
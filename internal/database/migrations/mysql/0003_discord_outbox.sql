@@ -0,0 +1,21 @@
+-- Operaciones de Discord pendientes (ver internal/outbox). Se registran en la
+-- misma transacción que la evaluación y un despachador las aplica después.
+
+CREATE TABLE IF NOT EXISTS discord_outbox (
+	id           BIGINT AUTO_INCREMENT PRIMARY KEY,
+	ref          CHAR(16)     NOT NULL,
+	incident_key VARCHAR(255) NOT NULL,
+	operation    VARCHAR(16)  NOT NULL,
+	channel_id   VARCHAR(255) NOT NULL,
+	message_id   VARCHAR(255) NOT NULL DEFAULT '',
+	assignee_id  VARCHAR(128) NOT NULL DEFAULT '',
+	assignee     VARCHAR(255) NOT NULL DEFAULT '',
+	payload      MEDIUMTEXT   NULL,
+	status       VARCHAR(16)  NOT NULL DEFAULT 'pending',
+	attempts     INT          NOT NULL DEFAULT 0,
+	last_error   TEXT         NULL,
+	created_at   DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at   DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE KEY unique_outbox_ref (ref),
+	INDEX idx_outbox_status (status, incident_key)
+);
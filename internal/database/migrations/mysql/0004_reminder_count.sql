@@ -0,0 +1,3 @@
+-- discord_messages.reminder_count: recordatorios enviados desde la última
+-- evaluación publicada. La columna la agrega addReminderCount (migrate.go):
+-- ALTER TABLE ... ADD COLUMN no es idempotente en todos los motores.
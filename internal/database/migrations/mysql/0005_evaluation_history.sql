@@ -0,0 +1,39 @@
+-- Historial de evaluaciones y resúmenes publicados (ver internal/report).
+-- incident_evaluations guarda solo la última evaluación de cada incidencia;
+-- evaluation_history guarda todas, con el assignee y el título del momento.
+-- Se siembra con las evaluaciones existentes para que el primer resumen no
+-- salga vacío.
+
+CREATE TABLE IF NOT EXISTS evaluation_history (
+	id             BIGINT AUTO_INCREMENT PRIMARY KEY,
+	incident_key   VARCHAR(255) NOT NULL,
+	title          VARCHAR(512) NOT NULL DEFAULT '',
+	assignee_id    VARCHAR(128) NOT NULL DEFAULT '',
+	assignee       VARCHAR(255) NOT NULL DEFAULT '',
+	prompt_set     VARCHAR(100) NOT NULL DEFAULT '',
+	prompt_version VARCHAR(100) NOT NULL DEFAULT '',
+	phase_results  JSON         NOT NULL,
+	evaluated_at   DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	INDEX idx_history_evaluated (evaluated_at),
+	INDEX idx_history_incident (incident_key)
+);
+
+CREATE TABLE IF NOT EXISTS digest_runs (
+	id           BIGINT AUTO_INCREMENT PRIMARY KEY,
+	name         VARCHAR(100) NOT NULL,
+	channel_id   VARCHAR(255) NOT NULL,
+	message_id   VARCHAR(255) NOT NULL DEFAULT '',
+	period_start DATETIME     NOT NULL,
+	period_end   DATETIME     NOT NULL,
+	evaluated    INT          NOT NULL DEFAULT 0,
+	created_at   DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE KEY unique_digest_period (name, period_end)
+);
+
+INSERT INTO evaluation_history (incident_key, assignee_id, assignee, prompt_version, phase_results, evaluated_at)
+SELECT e.incident_key,
+	COALESCE((SELECT MAX(m.assignee_id) FROM discord_messages m WHERE m.incident_key = e.incident_key), ''),
+	COALESCE((SELECT MAX(m.assignee) FROM discord_messages m WHERE m.incident_key = e.incident_key), ''),
+	e.prompt_version, e.phase_results, e.evaluated_at
+FROM incident_evaluations e
+WHERE e.phase_results IS NOT NULL AND NOT EXISTS (SELECT 1 FROM evaluation_history);
@@ -0,0 +1,14 @@
+-- Recordatorios enviados como respuesta a un mensaje de evaluación
+-- (DISCORD_REMINDER_MODE=reply). Se borran de Discord junto con el mensaje
+-- recordado (ver internal/outbox).
+
+CREATE TABLE IF NOT EXISTS discord_reminders (
+	id           BIGINT AUTO_INCREMENT PRIMARY KEY,
+	incident_key VARCHAR(255) NOT NULL,
+	channel_id   VARCHAR(255) NOT NULL,
+	message_id   VARCHAR(255) NOT NULL,
+	reminder_id  VARCHAR(255) NOT NULL,
+	created_at   DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE KEY unique_reminder (channel_id, reminder_id),
+	INDEX idx_reminders_message (channel_id, message_id)
+);
@@ -0,0 +1,32 @@
+-- Esquema inicial. phase_results es JSONB: los puntajes se pueden consultar
+-- con los operadores de JSON de PostgreSQL, y el índice GIN acelera los
+-- filtros con @>.
+
+CREATE TABLE IF NOT EXISTS discord_messages (
+	id BIGSERIAL PRIMARY KEY,
+	incident_key VARCHAR(255) NOT NULL,
+	channel_id VARCHAR(255) NOT NULL,
+	message_id VARCHAR(255) NOT NULL,
+	assignee VARCHAR(255) NOT NULL,
+	assignee_id VARCHAR(128) NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ DEFAULT now(),
+	last_notification TIMESTAMPTZ DEFAULT now(),
+	CONSTRAINT unique_incident_channel UNIQUE (incident_key, channel_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_key ON discord_messages (incident_key);
+
+CREATE INDEX IF NOT EXISTS idx_channel_message ON discord_messages (channel_id, message_id);
+
+CREATE TABLE IF NOT EXISTS incident_evaluations (
+	incident_key    VARCHAR(50)  NOT NULL PRIMARY KEY,
+	jira_updated_at TIMESTAMPTZ  NOT NULL,
+	phase_results   JSONB        NOT NULL,
+	input_hash      CHAR(64)     NOT NULL DEFAULT '',
+	prompt_version  VARCHAR(100) NOT NULL DEFAULT '',
+	evaluated_at    TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_updated ON incident_evaluations (jira_updated_at);
+
+CREATE INDEX IF NOT EXISTS idx_phase_results ON incident_evaluations USING GIN (phase_results jsonb_path_ops);
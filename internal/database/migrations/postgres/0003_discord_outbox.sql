@@ -0,0 +1,22 @@
+-- Operaciones de Discord pendientes (ver internal/outbox). Se registran en la
+-- misma transacción que la evaluación y un despachador las aplica después.
+
+CREATE TABLE IF NOT EXISTS discord_outbox (
+	id           BIGSERIAL    PRIMARY KEY,
+	ref          CHAR(16)     NOT NULL,
+	incident_key VARCHAR(255) NOT NULL,
+	operation    VARCHAR(16)  NOT NULL,
+	channel_id   VARCHAR(255) NOT NULL,
+	message_id   VARCHAR(255) NOT NULL DEFAULT '',
+	assignee_id  VARCHAR(128) NOT NULL DEFAULT '',
+	assignee     VARCHAR(255) NOT NULL DEFAULT '',
+	payload      TEXT         NULL,
+	status       VARCHAR(16)  NOT NULL DEFAULT 'pending',
+	attempts     INTEGER      NOT NULL DEFAULT 0,
+	last_error   TEXT         NULL,
+	created_at   TIMESTAMPTZ  NOT NULL DEFAULT now(),
+	updated_at   TIMESTAMPTZ  NOT NULL DEFAULT now(),
+	CONSTRAINT unique_outbox_ref UNIQUE (ref)
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_status ON discord_outbox (status, incident_key);
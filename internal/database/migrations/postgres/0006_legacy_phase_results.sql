@@ -0,0 +1,4 @@
+-- Evaluaciones anteriores al pipeline de fases: phase1_result/phase2_result se
+-- convierten a phase_results y se agregan al historial, que las omitió al
+-- sembrarse en 0005. La conversión la hace backfillPhaseResults (legacy.go):
+-- el formato de phase_results no se puede armar con SQL portable.
@@ -0,0 +1,15 @@
+-- Recordatorios enviados como respuesta a un mensaje de evaluación
+-- (DISCORD_REMINDER_MODE=reply). Se borran de Discord junto con el mensaje
+-- recordado (ver internal/outbox).
+
+CREATE TABLE IF NOT EXISTS discord_reminders (
+	id           BIGSERIAL    PRIMARY KEY,
+	incident_key VARCHAR(255) NOT NULL,
+	channel_id   VARCHAR(255) NOT NULL,
+	message_id   VARCHAR(255) NOT NULL,
+	reminder_id  VARCHAR(255) NOT NULL,
+	created_at   TIMESTAMPTZ  NOT NULL DEFAULT now(),
+	CONSTRAINT unique_reminder UNIQUE (channel_id, reminder_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminders_message ON discord_reminders (channel_id, message_id);
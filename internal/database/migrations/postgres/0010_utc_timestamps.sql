@@ -0,0 +1,2 @@
+-- Solo cambia SQLite, que compara las horas como texto: el historial y los
+-- resúmenes pasan a escribirse en UTC. Este archivo reserva la versión.
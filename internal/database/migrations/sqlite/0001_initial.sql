@@ -0,0 +1,28 @@
+-- Esquema inicial
+
+CREATE TABLE IF NOT EXISTS discord_messages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	incident_key TEXT NOT NULL,
+	channel_id TEXT NOT NULL,
+	message_id TEXT NOT NULL,
+	assignee TEXT NOT NULL,
+	assignee_id TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_notification DATETIME DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT unique_incident_channel UNIQUE (incident_key, channel_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_key ON discord_messages (incident_key);
+
+CREATE INDEX IF NOT EXISTS idx_channel_message ON discord_messages (channel_id, message_id);
+
+CREATE TABLE IF NOT EXISTS incident_evaluations (
+	incident_key    TEXT     NOT NULL PRIMARY KEY,
+	jira_updated_at DATETIME NOT NULL,
+	phase_results   TEXT     NOT NULL,
+	input_hash      TEXT     NOT NULL DEFAULT '',
+	prompt_version  TEXT     NOT NULL DEFAULT '',
+	evaluated_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_updated ON incident_evaluations (jira_updated_at);
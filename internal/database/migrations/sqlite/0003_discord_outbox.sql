@@ -0,0 +1,22 @@
+-- Operaciones de Discord pendientes (ver internal/outbox). Se registran en la
+-- misma transacción que la evaluación y un despachador las aplica después.
+
+CREATE TABLE IF NOT EXISTS discord_outbox (
+	id           INTEGER  PRIMARY KEY AUTOINCREMENT,
+	ref          TEXT     NOT NULL,
+	incident_key TEXT     NOT NULL,
+	operation    TEXT     NOT NULL,
+	channel_id   TEXT     NOT NULL,
+	message_id   TEXT     NOT NULL DEFAULT '',
+	assignee_id  TEXT     NOT NULL DEFAULT '',
+	assignee     TEXT     NOT NULL DEFAULT '',
+	payload      TEXT     NULL,
+	status       TEXT     NOT NULL DEFAULT 'pending',
+	attempts     INTEGER  NOT NULL DEFAULT 0,
+	last_error   TEXT     NULL,
+	created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT unique_outbox_ref UNIQUE (ref)
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_status ON discord_outbox (status, incident_key);
@@ -0,0 +1,41 @@
+-- Historial de evaluaciones y resúmenes publicados (ver internal/report).
+-- incident_evaluations guarda solo la última evaluación de cada incidencia;
+-- evaluation_history guarda todas, con el assignee y el título del momento.
+-- Se siembra con las evaluaciones existentes para que el primer resumen no
+-- salga vacío.
+
+CREATE TABLE IF NOT EXISTS evaluation_history (
+	id             INTEGER  PRIMARY KEY AUTOINCREMENT,
+	incident_key   TEXT     NOT NULL,
+	title          TEXT     NOT NULL DEFAULT '',
+	assignee_id    TEXT     NOT NULL DEFAULT '',
+	assignee       TEXT     NOT NULL DEFAULT '',
+	prompt_set     TEXT     NOT NULL DEFAULT '',
+	prompt_version TEXT     NOT NULL DEFAULT '',
+	phase_results  TEXT     NOT NULL,
+	evaluated_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_history_evaluated ON evaluation_history (evaluated_at);
+
+CREATE INDEX IF NOT EXISTS idx_history_incident ON evaluation_history (incident_key);
+
+CREATE TABLE IF NOT EXISTS digest_runs (
+	id           INTEGER  PRIMARY KEY AUTOINCREMENT,
+	name         TEXT     NOT NULL,
+	channel_id   TEXT     NOT NULL,
+	message_id   TEXT     NOT NULL DEFAULT '',
+	period_start DATETIME NOT NULL,
+	period_end   DATETIME NOT NULL,
+	evaluated    INTEGER  NOT NULL DEFAULT 0,
+	created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT unique_digest_period UNIQUE (name, period_end)
+);
+
+INSERT INTO evaluation_history (incident_key, assignee_id, assignee, prompt_version, phase_results, evaluated_at)
+SELECT e.incident_key,
+	COALESCE((SELECT MAX(m.assignee_id) FROM discord_messages m WHERE m.incident_key = e.incident_key), ''),
+	COALESCE((SELECT MAX(m.assignee) FROM discord_messages m WHERE m.incident_key = e.incident_key), ''),
+	e.prompt_version, e.phase_results, e.evaluated_at
+FROM incident_evaluations e
+WHERE e.phase_results IS NOT NULL AND NOT EXISTS (SELECT 1 FROM evaluation_history);
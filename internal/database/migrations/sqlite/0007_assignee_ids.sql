@@ -0,0 +1,4 @@
+-- Registros de discord_messages anteriores a accountId (clave única por nombre
+-- visible): se completa assignee_id y la clave pasa a (incident_key,
+-- assignee_id). Lo hace migrateAssigneeIDs (sql.go), porque traducir nombres a
+-- accountId necesita consultar Jira. No hace nada en bases creadas desde cero.
@@ -0,0 +1,4 @@
+-- La clave única de discord_messages pasa a (incident_key, channel_id): con las
+-- reglas de ruteo una incidencia tiene un mensaje por canal. Lo hace
+-- migrateMessageChannels (sql.go), que además borra por el outbox los mensajes
+-- duplicados que descarta. No hace nada en bases creadas desde cero.
@@ -0,0 +1,15 @@
+-- Recordatorios enviados como respuesta a un mensaje de evaluación
+-- (DISCORD_REMINDER_MODE=reply). Se borran de Discord junto con el mensaje
+-- recordado (ver internal/outbox).
+
+CREATE TABLE IF NOT EXISTS discord_reminders (
+	id           INTEGER  PRIMARY KEY AUTOINCREMENT,
+	incident_key TEXT     NOT NULL,
+	channel_id   TEXT     NOT NULL,
+	message_id   TEXT     NOT NULL,
+	reminder_id  TEXT     NOT NULL,
+	created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT unique_reminder UNIQUE (channel_id, reminder_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminders_message ON discord_reminders (channel_id, message_id);
@@ -0,0 +1,11 @@
+-- SQLite guarda las horas como texto con la zona horaria y las compara como
+-- texto. El historial y los resúmenes se escriben en UTC desde esta versión;
+-- las filas anteriores se pasan a UTC para que los rangos y el orden funcionen.
+
+UPDATE evaluation_history SET evaluated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', evaluated_at)
+WHERE evaluated_at NOT LIKE '%+00:00' AND strftime('%Y-%m-%d %H:%M:%f+00:00', evaluated_at) IS NOT NULL;
+
+UPDATE digest_runs SET period_start = strftime('%Y-%m-%d %H:%M:%f+00:00', period_start),
+	period_end = strftime('%Y-%m-%d %H:%M:%f+00:00', period_end),
+	created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', created_at)
+WHERE period_end NOT LIKE '%+00:00' AND strftime('%Y-%m-%d %H:%M:%f+00:00', period_end) IS NOT NULL;
@@ -0,0 +1,9 @@
+-- Los resúmenes se publican por el outbox: digest_runs.ref identifica la
+-- operación que lo envía (la columna la agrega addDigestRef, migrate.go).
+-- digest_schedules guarda desde cuándo existe cada resumen, para calcular su
+-- primera publicación sin depender del arranque del proceso.
+
+CREATE TABLE IF NOT EXISTS digest_schedules (
+	name       TEXT PRIMARY KEY,
+	started_at DATETIME NOT NULL
+);
@@ -0,0 +1,267 @@
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Operaciones del outbox de Discord (migración 0003, ver internal/outbox)
+const (
+	OutboxSend   = "send"   // publicar Payload en ChannelID
+	OutboxDelete = "delete" // borrar MessageID de ChannelID y sus recordatorios
+	OutboxReply  = "reply"  // recordatorio: responder a MessageID
+	OutboxRepost = "repost" // recordatorio: volver a publicar; MessageID se borra con un OutboxDelete
+	OutboxNotice = "notice" // aviso informativo (p. ej. de reasignación): publicar Payload sin registrarlo
+	OutboxDigest = "digest" // resumen periódico: publicar Payload y guardar el mensaje en digest_runs
+)
+
+// Estados de una operación del outbox
+const (
+	OutboxPending  = "pending"
+	OutboxDone     = "done"
+	OutboxFailed   = "failed"     // agotó los reintentos
+	OutboxReplaced = "superseded" // envío reemplazado por el de una evaluación más nueva antes de aplicarse
+)
+
+// OutboxEntry es una operación de Discord registrada para aplicarse después
+type OutboxEntry struct {
+	ID          int64
+	Ref         string // identificador único; va en el pie del embed para reconocer un envío ya hecho
+	IncidentKey string
+	Operation   string // OutboxSend, OutboxDelete, OutboxReply u OutboxRepost
+	ChannelID   string
+	MessageID   string // mensaje a borrar o recordado; el enviado en OutboxSend al completarse
+	AssigneeID  string // a quién estaba asignada al planificar el envío
+	Assignee    string
+	Payload     string // mensaje a enviar en JSON (ver discord.Client.EvaluationPayload y ReminderPayload)
+	Attempts    int
+	CreatedAt   time.Time
+}
+
+// NewOutboxRef genera el identificador de una operación. Los envíos lo
+// necesitan antes de registrarse porque va dentro del embed.
+func NewOutboxRef() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// SaveEvaluation guarda la evaluación, la agrega al historial y registra las
+// operaciones de Discord que la publican en una sola transacción: o queda todo
+// o nada. record trae la incidencia, las fases y la versión de prompts. Los
+// envíos y recordatorios de la incidencia que seguían pendientes (p. ej. en
+// espera del horario laboral) se descartan: publicarían la evaluación anterior.
+// Los borrados pendientes se conservan.
+func (c *Client) SaveEvaluation(record HistoryEntry, jiraUpdatedAt time.Time, inputHash string, entries []OutboxEntry) error {
+	incidentKey := record.IncidentKey
+	return c.inTx(func(tx *sql.Tx) error {
+		if err := c.upsertEvaluation(tx, incidentKey, jiraUpdatedAt, inputHash, record.PromptVersion, record.PhaseResults); err != nil {
+			return err
+		}
+		if err := c.insertHistory(tx, record); err != nil {
+			return err
+		}
+		if _, err := c.execOn(tx, `UPDATE discord_outbox SET status = ?, updated_at = ?
+		WHERE incident_key = ? AND status = ? AND operation IN (?, ?, ?)`,
+			OutboxReplaced, time.Now(), incidentKey, OutboxPending, OutboxSend, OutboxReply, OutboxRepost); err != nil {
+			return fmt.Errorf("error descartando envíos pendientes de %s: %v", incidentKey, err)
+		}
+		return c.enqueue(tx, entries)
+	})
+}
+
+// EnqueueOutbox registra operaciones sin evaluación nueva (publicación desde la
+// caché, limpieza de una reasignación)
+func (c *Client) EnqueueOutbox(entries []OutboxEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return c.inTx(func(tx *sql.Tx) error {
+		return c.enqueue(tx, entries)
+	})
+}
+
+func (c *Client) enqueue(ex execer, entries []OutboxEntry) error {
+	now := time.Now()
+	for _, e := range entries {
+		if e.Ref == "" {
+			e.Ref = NewOutboxRef()
+		}
+		_, err := c.execOn(ex, `INSERT INTO discord_outbox (ref, incident_key, operation, channel_id, message_id, assignee_id, assignee, payload, status, attempts, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?)`,
+			e.Ref, e.IncidentKey, e.Operation, e.ChannelID, e.MessageID, e.AssigneeID, e.Assignee, e.Payload, OutboxPending, now, now)
+		if err != nil {
+			return fmt.Errorf("error registrando operación %s de %s en el outbox: %v", e.Operation, e.IncidentKey, err)
+		}
+	}
+	return nil
+}
+
+// PendingOutbox devuelve las operaciones pendientes de una incidencia ("" =
+// todas) en el orden en que se registraron
+func (c *Client) PendingOutbox(incidentKey string) ([]OutboxEntry, error) {
+	query := `SELECT id, ref, incident_key, operation, channel_id, message_id, assignee_id, assignee, payload, attempts, created_at
+	FROM discord_outbox WHERE status = ?`
+	args := []interface{}{OutboxPending}
+	if incidentKey != "" {
+		query += ` AND incident_key = ?`
+		args = append(args, incidentKey)
+	}
+	query += ` ORDER BY id`
+
+	rows, err := c.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando outbox: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []OutboxEntry
+	for rows.Next() {
+		var e OutboxEntry
+		var payload sql.NullString
+		if err := rows.Scan(&e.ID, &e.Ref, &e.IncidentKey, &e.Operation, &e.ChannelID, &e.MessageID,
+			&e.AssigneeID, &e.Assignee, &payload, &e.Attempts, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error escaneando outbox: %v", err)
+		}
+		e.Payload = payload.String
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// StartOutboxAttempt cuenta un intento antes de llamar a Discord. Si el proceso
+// se corta a mitad, el contador indica que el envío pudo haberse hecho.
+func (c *Client) StartOutboxAttempt(id int64) error {
+	if _, err := c.exec(`UPDATE discord_outbox SET attempts = attempts + 1, updated_at = ? WHERE id = ?`, time.Now(), id); err != nil {
+		return fmt.Errorf("error registrando intento de la operación %d: %v", id, err)
+	}
+	return nil
+}
+
+// CompleteOutboxSend registra el mensaje enviado en discord_messages y marca la
+// operación como hecha, en una transacción. Con lease solo se registra si la
+// instancia sigue siendo líder; si no, devuelve ErrLeaseLost y no cambia nada.
+// Lo mismo vale para CompleteOutboxReminder y CompleteOutboxDelete.
+func (c *Client) CompleteOutboxSend(entry OutboxEntry, messageID string, lease Lease) error {
+	return c.inTx(func(tx *sql.Tx) error {
+		if err := c.upsertMessage(tx, entry.IncidentKey, entry.ChannelID, messageID, entry.AssigneeID, entry.Assignee); err != nil {
+			return err
+		}
+		return c.completeOutbox(tx, entry.ID, messageID, lease)
+	})
+}
+
+// CompleteOutboxReminder cuenta el recordatorio en el mensaje recordado y marca
+// la operación como hecha. messageID es el mensaje que queda registrado: el
+// mismo al responder, el nuevo al volver a publicar. reminderID es la respuesta
+// enviada, que se registra para borrarla junto con el mensaje; vacío al volver
+// a publicar.
+func (c *Client) CompleteOutboxReminder(entry OutboxEntry, messageID, reminderID string, lease Lease) error {
+	return c.inTx(func(tx *sql.Tx) error {
+		if _, err := c.execOn(tx, `UPDATE discord_messages SET message_id = ?, last_notification = ?, reminder_count = reminder_count + 1
+		WHERE incident_key = ? AND channel_id = ? AND message_id = ?`,
+			messageID, time.Now(), entry.IncidentKey, entry.ChannelID, entry.MessageID); err != nil {
+			return fmt.Errorf("error registrando recordatorio de %s: %v", entry.IncidentKey, err)
+		}
+		if reminderID != "" {
+			if _, err := c.execOn(tx, `INSERT INTO discord_reminders (incident_key, channel_id, message_id, reminder_id, created_at) VALUES (?, ?, ?, ?, ?)`,
+				entry.IncidentKey, entry.ChannelID, messageID, reminderID, time.Now()); err != nil {
+				return fmt.Errorf("error registrando recordatorio de %s: %v", entry.IncidentKey, err)
+			}
+		}
+		return c.completeOutbox(tx, entry.ID, messageID, lease)
+	})
+}
+
+// CompleteOutboxDelete quita el registro del mensaje borrado y los de sus
+// recordatorios, y marca la operación como hecha. El registro se quita solo si
+// sigue apuntando a ese mensaje: al reemplazar una evaluación el envío nuevo ya
+// lo actualizó.
+func (c *Client) CompleteOutboxDelete(entry OutboxEntry, lease Lease) error {
+	return c.inTx(func(tx *sql.Tx) error {
+		if _, err := c.execOn(tx, `DELETE FROM discord_messages WHERE incident_key = ? AND channel_id = ? AND message_id = ?`,
+			entry.IncidentKey, entry.ChannelID, entry.MessageID); err != nil {
+			return fmt.Errorf("error eliminando mensaje de BD: %v", err)
+		}
+		if _, err := c.execOn(tx, `DELETE FROM discord_reminders WHERE channel_id = ? AND message_id = ?`,
+			entry.ChannelID, entry.MessageID); err != nil {
+			return fmt.Errorf("error eliminando recordatorios de BD: %v", err)
+		}
+		return c.completeOutbox(tx, entry.ID, entry.MessageID, lease)
+	})
+}
+
+// CompleteOutboxNotice marca como hecho un aviso. Los avisos no se registran en
+// discord_messages: no se borran ni se recuerdan.
+func (c *Client) CompleteOutboxNotice(entry OutboxEntry, messageID string, lease Lease) error {
+	return c.inTx(func(tx *sql.Tx) error {
+		return c.completeOutbox(tx, entry.ID, messageID, lease)
+	})
+}
+
+// CompleteOutboxDigest guarda el mensaje del resumen en digest_runs y marca la
+// operación como hecha
+func (c *Client) CompleteOutboxDigest(entry OutboxEntry, messageID string, lease Lease) error {
+	return c.inTx(func(tx *sql.Tx) error {
+		if _, err := c.execOn(tx, `UPDATE digest_runs SET message_id = ? WHERE ref = ?`, messageID, entry.Ref); err != nil {
+			return fmt.Errorf("error registrando resumen %s: %v", entry.IncidentKey, err)
+		}
+		return c.completeOutbox(tx, entry.ID, messageID, lease)
+	})
+}
+
+// FailOutbox registra el error de un intento. Con final la operación queda
+// como fallida y no se reintenta.
+func (c *Client) FailOutbox(id int64, errMsg string, final bool) error {
+	if final {
+		return c.finishOutbox(c.db, id, OutboxFailed, "", errMsg)
+	}
+	if _, err := c.exec(`UPDATE discord_outbox SET last_error = ?, updated_at = ? WHERE id = ?`, errMsg, time.Now(), id); err != nil {
+		return fmt.Errorf("error registrando fallo de la operación %d: %v", id, err)
+	}
+	return nil
+}
+
+// completeOutbox marca la operación como hecha si la instancia sigue teniendo
+// el lease. Al devolver ErrLeaseLost la transacción se revierte entera.
+func (c *Client) completeOutbox(tx *sql.Tx, id int64, messageID string, lease Lease) error {
+	query, args := lease.fence(`UPDATE discord_outbox SET status = ?, last_error = ?, updated_at = ?, message_id = ? WHERE id = ?`,
+		[]interface{}{OutboxDone, "", time.Now(), messageID, id})
+	result, err := c.execOn(tx, query, args...)
+	if err != nil {
+		return fmt.Errorf("error actualizando la operación %d del outbox: %v", id, err)
+	}
+	if lease.Name == "" {
+		return nil
+	}
+	// El UPDATE siempre cambia status, así que MySQL también cuenta la fila
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (c *Client) finishOutbox(ex execer, id int64, status, messageID, errMsg string) error {
+	query := `UPDATE discord_outbox SET status = ?, last_error = ?, updated_at = ? WHERE id = ?`
+	args := []interface{}{status, errMsg, time.Now(), id}
+	if messageID != "" {
+		query = `UPDATE discord_outbox SET status = ?, last_error = ?, updated_at = ?, message_id = ? WHERE id = ?`
+		args = []interface{}{status, errMsg, time.Now(), messageID, id}
+	}
+	if _, err := c.execOn(ex, query, args...); err != nil {
+		return fmt.Errorf("error actualizando la operación %d del outbox: %v", id, err)
+	}
+	return nil
+}
+
+// PruneOutbox borra las operaciones terminadas (hechas o fallidas) antes de before
+func (c *Client) PruneOutbox(before time.Time) (int64, error) {
+	result, err := c.exec(`DELETE FROM discord_outbox WHERE status <> ? AND updated_at < ?`, OutboxPending, before)
+	if err != nil {
+		return 0, fmt.Errorf("error depurando outbox: %v", err)
+	}
+	return result.RowsAffected()
+}
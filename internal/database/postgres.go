@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/PhelGc/furina-sync/internal/config"
+	_ "github.com/lib/pq"
+)
+
+var postgresDialect = &dialect{
+	name: config.DriverPostgres,
+	migrationsTable: `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER      NOT NULL PRIMARY KEY,
+		name       VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+	);`,
+	columnQuery:  `SELECT is_nullable = 'YES' FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`,
+	indexQuery:   `SELECT COUNT(*) FROM pg_indexes WHERE schemaname = current_schema() AND tablename = ? AND indexname = ?`,
+	upsertClause: onConflictUpdate,
+	placeholder: func(n int) string {
+		return "$" + strconv.Itoa(n)
+	},
+	// Bloqueo de sesión: se libera también si la conexión se corta
+	lock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(hashtext('furina_sync_migrations'))`)
+		return err
+	},
+	unlock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext('furina_sync_migrations'))`)
+		return err
+	},
+}
+
+// openPostgres abre la conexión a PostgreSQL
+func openPostgres(config *Config) (*sql.DB, error) {
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(config.Username, config.Password),
+		Host:   config.Host + ":" + config.Port,
+		Path:   "/" + config.Database,
+	}
+	if config.SSLMode != "" {
+		dsn.RawQuery = url.Values{"sslmode": {config.SSLMode}}.Encode()
+	}
+
+	db, err := sql.Open("postgres", dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("error conectando a PostgreSQL: %v", err)
+	}
+
+	// Mismo pool que MySQL: ajustado al número de workers concurrentes
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error haciendo ping a PostgreSQL: %v", err)
+	}
+
+	log.Printf("Conexión establecida con PostgreSQL: %s:%s", config.Host, config.Port)
+	return db, nil
+}
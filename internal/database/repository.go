@@ -0,0 +1,84 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/PhelGc/furina-sync/internal/config"
+)
+
+// Repository es el almacenamiento de mensajes de Discord y evaluaciones. El
+// resto del sistema depende solo de esta interfaz, no del motor.
+type Repository interface {
+	// Esquema
+	Migrate() error
+	MigrationStatus() ([]MigrationState, error)
+
+	// Evaluaciones
+	GetEvaluationsByKeys(keys []string) (map[string]*CachedEvaluation, error)
+	UpsertEvaluation(incidentKey string, jiraUpdatedAt time.Time, inputHash, promptVersion, phasesJSON string) error
+
+	// Mensajes
+	GetExistingMessage(incidentKey, channelID string) (*MessageToDelete, error)
+	UpsertMessage(incidentKey, channelID, messageID, assigneeID, assignee string) error
+	DeleteMessage(incidentKey, channelID, messageID string, lease Lease) error
+	RekeyAssignee(incidentKey, fromAssigneeID, toAssigneeID string) error
+	GetAllActiveMessages() ([]MessageToDelete, error)
+	GetMessagesByKeys(keys []string) (map[string][]*MessageToDelete, error)
+	ReminderMessages(channelID, messageID string) ([]string, error)
+
+	// Limpieza
+	CleanupRemovedIncidents(currentIncidentKeys []string) ([]MessageToDelete, error)
+
+	// Outbox de Discord (ver internal/outbox)
+	SaveEvaluation(record HistoryEntry, jiraUpdatedAt time.Time, inputHash string, entries []OutboxEntry) error
+	EnqueueOutbox(entries []OutboxEntry) error
+	PendingOutbox(incidentKey string) ([]OutboxEntry, error)
+	StartOutboxAttempt(id int64) error
+	CompleteOutboxSend(entry OutboxEntry, messageID string, lease Lease) error
+	CompleteOutboxDelete(entry OutboxEntry, lease Lease) error
+	CompleteOutboxReminder(entry OutboxEntry, messageID, reminderID string, lease Lease) error
+	CompleteOutboxNotice(entry OutboxEntry, messageID string, lease Lease) error
+	CompleteOutboxDigest(entry OutboxEntry, messageID string, lease Lease) error
+	FailOutbox(id int64, errMsg string, final bool) error
+	PruneOutbox(before time.Time) (int64, error)
+
+	// Historial y resúmenes (ver internal/report)
+	EvaluationHistory(from, to time.Time) ([]HistoryEntry, error)
+	LastDigestRun(name string) (*DigestRun, error)
+	EnqueueDigest(run DigestRun, entry OutboxEntry, lease Lease) error
+	DigestStart(name string, now time.Time) (time.Time, error)
+
+	// Elección de líder (ver internal/leader)
+	AcquireLease(name, holder string, ttl time.Duration) (bool, error)
+	ReleaseLease(name, holder string) error
+	LeaseHolder(name string) (string, time.Time, error)
+
+	Close() error
+}
+
+// Open conecta con el motor indicado en cfg.Driver
+func Open(cfg *Config) (Repository, error) {
+	switch cfg.Driver {
+	case "", config.DriverMySQL:
+		db, err := openMySQL(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &Client{db: db, dialect: mysqlDialect, resolveAssignee: cfg.ResolveAssignee}, nil
+	case config.DriverSQLite:
+		db, err := openSQLite(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &Client{db: db, dialect: sqliteDialect, resolveAssignee: cfg.ResolveAssignee}, nil
+	case config.DriverPostgres:
+		db, err := openPostgres(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &Client{db: db, dialect: postgresDialect, resolveAssignee: cfg.ResolveAssignee}, nil
+	default:
+		return nil, fmt.Errorf("DB_DRIVER inválido: %q (valores: mysql, sqlite, postgres)", cfg.Driver)
+	}
+}
@@ -0,0 +1,577 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Client implementa Repository sobre database/sql. Lo que cambia entre motores
+// (DDL, upserts, consultas de esquema) lo resuelve el dialecto.
+type Client struct {
+	db      *sql.DB
+	dialect *dialect
+
+	resolveAssignee func(displayName string) (string, error) // ver Config.ResolveAssignee
+}
+
+type Config struct {
+	Driver   string // mysql (por defecto), sqlite o postgres
+	Host     string
+	Port     string
+	Username string
+	Password string
+	Database string
+	Path     string // archivo de SQLite; ":memory:" para una base en memoria
+	SSLMode  string // sslmode de PostgreSQL; vacío = el del driver (require)
+
+	// ResolveAssignee traduce un nombre visible a accountId en la migración
+	// 0007. nil = los nombres quedan como LegacyAssigneeID hasta que la
+	// incidencia vuelva a sincronizarse.
+	ResolveAssignee func(displayName string) (string, error)
+}
+
+// CachedEvaluation representa una evaluación IA guardada en BD
+type CachedEvaluation struct {
+	IncidentKey   string
+	JiraUpdatedAt time.Time
+	InputHash     string // hash de las entradas evaluadas; vacío en filas anteriores al hash
+	PromptVersion string // versión de los prompts con que se evaluó
+	PhaseResults  string // JSON de las fases (ver evaluator.EvaluationResult.PhasesJSON); vacío en filas antiguas
+}
+
+type MessageToDelete struct {
+	ID               int       `json:"id"`
+	IncidentKey      string    `json:"incident_key"`
+	ChannelID        string    `json:"channel_id"`
+	MessageID        string    `json:"message_id"`
+	Assignee         string    `json:"assignee"`    // nombre visible, solo para logs
+	AssigneeID       string    `json:"assignee_id"` // accountId de Jira
+	CreatedAt        time.Time `json:"created_at"`
+	LastNotification time.Time `json:"last_notification"` // último envío o recordatorio
+	ReminderCount    int       `json:"reminder_count"`    // recordatorios desde la última evaluación publicada
+}
+
+// upgradeLegacySchema agrega las columnas que les faltan a las tablas creadas
+// por versiones anteriores a las migraciones, cuando el esquema se ajustaba al
+// arrancar. Es parte de la migración 0001 y es idempotente.
+func (c *Client) upgradeLegacySchema() error {
+	// Tablas anteriores a accountId: la clave única se migra en las migraciones
+	// 0007 y 0008
+	if err := c.ensureColumn("discord_messages", "assignee_id", "VARCHAR(128) NOT NULL DEFAULT '' AFTER assignee"); err != nil {
+		return err
+	}
+
+	// Tablas creadas antes del cache por hash no tienen la columna input_hash
+	if err := c.ensureColumn("incident_evaluations", "input_hash", "CHAR(64) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := c.ensureColumn("incident_evaluations", "prompt_version", "VARCHAR(100) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	// Tablas anteriores al pipeline de fases guardaban phase1_result/phase2_result;
+	// las filas nuevas solo escriben phase_results
+	if err := c.ensureColumn("incident_evaluations", "phase_results", "JSON NULL"); err != nil {
+		return err
+	}
+	return c.dropNotNull("incident_evaluations", "phase1_result", "JSON NULL")
+}
+
+// addReminderCount es la migración 0004
+func (c *Client) addReminderCount() error {
+	return c.ensureColumn("discord_messages", "reminder_count", "INTEGER NOT NULL DEFAULT 0")
+}
+
+// addDigestRef es la migración 0011: digest_runs.ref es la operación del
+// outbox que publica el resumen
+func (c *Client) addDigestRef() error {
+	return c.ensureColumn("digest_runs", "ref", "VARCHAR(32) NOT NULL DEFAULT ''")
+}
+
+// ensureColumn agrega una columna a una tabla existente si todavía no la tiene
+func (c *Client) ensureColumn(table, column, definition string) error {
+	exists, _, err := c.columnInfo(table, column)
+	if err != nil || exists {
+		return err
+	}
+
+	if _, err := c.exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("error agregando columna %s.%s: %v", table, column, err)
+	}
+	log.Printf("Columna %s.%s agregada", table, column)
+	return nil
+}
+
+// dropNotNull permite NULL en una columna existente. No hace nada si la columna
+// no existe o ya admite NULL.
+func (c *Client) dropNotNull(table, column, definition string) error {
+	exists, nullable, err := c.columnInfo(table, column)
+	if err != nil || !exists || nullable {
+		return err
+	}
+
+	if _, err := c.exec(fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("error modificando columna %s.%s: %v", table, column, err)
+	}
+	log.Printf("Columna %s.%s ahora admite NULL", table, column)
+	return nil
+}
+
+// columnInfo indica si la tabla tiene la columna y si esta admite NULL
+func (c *Client) columnInfo(table, column string) (exists, nullable bool, err error) {
+	err = c.queryRow(c.dialect.columnQuery, table, column).Scan(&nullable)
+	if err == sql.ErrNoRows {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("error verificando columna %s.%s: %v", table, column, err)
+	}
+	return true, nullable, nil
+}
+
+// indexExists indica si la tabla tiene un índice con ese nombre
+func (c *Client) indexExists(table, index string) (bool, error) {
+	var count int
+	err := c.queryRow(c.dialect.indexQuery, table, index).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("error verificando índice %s.%s: %v", table, index, err)
+	}
+	return count > 0, nil
+}
+
+// migrateAssigneeIDs es la migración 0007: completa assignee_id en los
+// registros creados cuando los mensajes se identificaban por nombre visible
+// (también en el historial, que se sembró con esos registros) y cambia la clave
+// única a (incident_key, assignee_id). Los nombres se traducen con
+// c.resolveAssignee; los que no se pueden resolver quedan como
+// LegacyAssigneeID para no perder el registro. Es idempotente: si ya no existe
+// la clave por nombre no hace nada, como en las bases creadas desde cero (y en
+// SQLite, que nunca tuvo ese esquema).
+func (c *Client) migrateAssigneeIDs() error {
+	legacy, err := c.indexExists("discord_messages", "unique_incident_assignee")
+	if err != nil || !legacy {
+		return err
+	}
+
+	rows, err := c.query(`SELECT DISTINCT assignee FROM discord_messages WHERE assignee_id = ''`)
+	if err != nil {
+		return fmt.Errorf("error consultando assignees sin accountId: %v", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err == nil {
+			names = append(names, name)
+		}
+	}
+	rows.Close()
+
+	if c.resolveAssignee == nil && len(names) > 0 {
+		log.Printf("Advertencia: sin acceso a Jira, %d assignee(s) quedan como name:<nombre> hasta la próxima sincronización", len(names))
+	}
+	for _, name := range names {
+		accountID := LegacyAssigneeID(name)
+		if c.resolveAssignee != nil {
+			if resolved, err := c.resolveAssignee(name); err != nil || resolved == "" {
+				log.Printf("Advertencia: no se pudo resolver accountId de %q: %v", name, err)
+			} else {
+				accountID = resolved
+			}
+		}
+		for _, table := range []string{"discord_messages", "evaluation_history"} {
+			if _, err := c.exec(`UPDATE `+table+` SET assignee_id = ? WHERE assignee = ? AND assignee_id = ''`, accountID, name); err != nil {
+				return fmt.Errorf("error completando accountId de %q en %s: %v", name, table, err)
+			}
+		}
+		log.Printf("Mensajes de %s migrados a accountId %s", name, accountID)
+	}
+
+	if _, err := c.exec(`ALTER TABLE discord_messages DROP INDEX unique_incident_assignee, ADD UNIQUE KEY unique_incident_assignee_id (incident_key, assignee_id)`); err != nil {
+		return fmt.Errorf("error cambiando clave única a accountId: %v", err)
+	}
+	log.Println("Tabla discord_messages migrada a accountId")
+	return nil
+}
+
+// LegacyAssigneeID es el assignee_id que reciben los registros cuyo nombre
+// visible no se pudo resolver a accountId
+func LegacyAssigneeID(displayName string) string {
+	return "name:" + displayName
+}
+
+// migrateMessageChannels es la migración 0008: cambia la clave única de
+// discord_messages de (incident_key, assignee_id) a (incident_key,
+// channel_id), porque con las reglas de ruteo una incidencia puede tener un
+// mensaje en varios canales. Si quedaran dos registros de la misma incidencia
+// en el mismo canal se conserva el más reciente, y el mensaje de Discord del
+// otro se borra por el outbox (0003). Es idempotente.
+func (c *Client) migrateMessageChannels() error {
+	legacy, err := c.indexExists("discord_messages", "unique_incident_assignee_id")
+	if err != nil || !legacy {
+		return err
+	}
+
+	rows, err := c.query(`
+	SELECT DISTINCT older.id, older.incident_key, older.channel_id, older.message_id, older.assignee_id, older.assignee
+	FROM discord_messages older
+	JOIN discord_messages newer
+		ON newer.incident_key = older.incident_key AND newer.channel_id = older.channel_id AND newer.id > older.id`)
+	if err != nil {
+		return fmt.Errorf("error consultando mensajes duplicados por canal: %v", err)
+	}
+	var ids []interface{}
+	var entries []OutboxEntry
+	for rows.Next() {
+		var id int
+		e := OutboxEntry{Operation: OutboxDelete}
+		if err := rows.Scan(&id, &e.IncidentKey, &e.ChannelID, &e.MessageID, &e.AssigneeID, &e.Assignee); err != nil {
+			rows.Close()
+			return fmt.Errorf("error escaneando mensaje duplicado: %v", err)
+		}
+		ids = append(ids, id)
+		entries = append(entries, e)
+	}
+	rows.Close()
+
+	// El registro se descarta ya para poder cambiar la clave; el borrado en
+	// Discord queda en el outbox y se aplica en el primer Replay
+	if len(ids) > 0 {
+		err := c.inTx(func(tx *sql.Tx) error {
+			placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+			if _, err := c.execOn(tx, `DELETE FROM discord_messages WHERE id IN (`+placeholders+`)`, ids...); err != nil {
+				return fmt.Errorf("error eliminando mensajes duplicados por canal: %v", err)
+			}
+			return c.enqueue(tx, entries)
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("Mensajes duplicados por canal descartados: %d (se borran de Discord por el outbox)", len(ids))
+	}
+	if _, err := c.exec(`ALTER TABLE discord_messages DROP INDEX unique_incident_assignee_id, ADD UNIQUE KEY unique_incident_channel (incident_key, channel_id)`); err != nil {
+		return fmt.Errorf("error cambiando clave única a canal: %v", err)
+	}
+	log.Println("Tabla discord_messages migrada a un mensaje por canal")
+	return nil
+}
+
+// GetEvaluationsByKeys carga el cache de evaluaciones para un conjunto de incidencias en una sola query.
+// Retorna un mapa incident_key → CachedEvaluation para comparar el hash de entradas.
+func (c *Client) GetEvaluationsByKeys(keys []string) (map[string]*CachedEvaluation, error) {
+	result := make(map[string]*CachedEvaluation)
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	placeholders := strings.Repeat("?,", len(keys))
+	placeholders = placeholders[:len(placeholders)-1]
+
+	query := fmt.Sprintf(
+		`SELECT incident_key, jira_updated_at, input_hash, prompt_version, phase_results FROM incident_evaluations WHERE incident_key IN (%s)`,
+		placeholders)
+
+	args := make([]interface{}, len(keys))
+	for i, k := range keys {
+		args[i] = k
+	}
+
+	rows, err := c.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error cargando evaluaciones por keys: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e CachedEvaluation
+		var phases sql.NullString
+		if err := rows.Scan(&e.IncidentKey, &e.JiraUpdatedAt, &e.InputHash, &e.PromptVersion, &phases); err != nil {
+			log.Printf("Error escaneando evaluación: %v", err)
+			continue
+		}
+		e.PhaseResults = phases.String
+		result[e.IncidentKey] = &e
+	}
+
+	return result, nil
+}
+
+// UpsertEvaluation inserta o actualiza el resultado de una evaluación IA.
+// phasesJSON es el resultado de todas las fases del pipeline (ver
+// evaluator.EvaluationResult.PhasesJSON). inputHash identifica las entradas
+// evaluadas (ver evaluator.Client.InputHash) y promptVersion la rúbrica usada.
+func (c *Client) UpsertEvaluation(incidentKey string, jiraUpdatedAt time.Time, inputHash, promptVersion, phasesJSON string) error {
+	return c.upsertEvaluation(c.db, incidentKey, jiraUpdatedAt, inputHash, promptVersion, phasesJSON)
+}
+
+func (c *Client) upsertEvaluation(ex execer, incidentKey string, jiraUpdatedAt time.Time, inputHash, promptVersion, phasesJSON string) error {
+	query := c.dialect.upsert("incident_evaluations",
+		[]string{"incident_key", "jira_updated_at", "phase_results", "input_hash", "prompt_version", "evaluated_at"},
+		[]string{"incident_key"},
+		[]string{"jira_updated_at", "phase_results", "input_hash", "prompt_version", "evaluated_at"})
+
+	_, err := c.execOn(ex, query, incidentKey, jiraUpdatedAt, phasesJSON, inputHash, promptVersion, time.Now())
+	if err != nil {
+		return fmt.Errorf("error guardando evaluación para %s: %v", incidentKey, err)
+	}
+	return nil
+}
+
+// GetExistingMessage obtiene el mensaje de una incidencia en un canal
+func (c *Client) GetExistingMessage(incidentKey, channelID string) (*MessageToDelete, error) {
+	query := `SELECT id, incident_key, channel_id, message_id, assignee, assignee_id, created_at, last_notification, reminder_count FROM discord_messages WHERE incident_key = ? AND channel_id = ?`
+
+	var msg MessageToDelete
+	err := c.queryRow(query, incidentKey, channelID).Scan(
+		&msg.ID, &msg.IncidentKey, &msg.ChannelID, &msg.MessageID, &msg.Assignee, &msg.AssigneeID, &msg.CreatedAt, &msg.LastNotification, &msg.ReminderCount)
+
+	if err == sql.ErrNoRows {
+		return nil, nil // No existe mensaje
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando mensaje existente: %v", err)
+	}
+
+	return &msg, nil
+}
+
+// UpsertMessage inserta o actualiza el mensaje de Discord de una incidencia en
+// un canal. assigneeID (accountId) y assignee (nombre visible) registran a quién
+// estaba asignada al enviarlo, para detectar reasignaciones.
+func (c *Client) UpsertMessage(incidentKey, channelID, messageID, assigneeID, assignee string) error {
+	return c.upsertMessage(c.db, incidentKey, channelID, messageID, assigneeID, assignee)
+}
+
+func (c *Client) upsertMessage(ex execer, incidentKey, channelID, messageID, assigneeID, assignee string) error {
+	// created_at solo se escribe al insertar: no está entre las columnas a
+	// actualizar. Un mensaje nuevo reinicia los recordatorios.
+	query := c.dialect.upsert("discord_messages",
+		[]string{"incident_key", "channel_id", "message_id", "assignee", "assignee_id", "created_at", "last_notification", "reminder_count"},
+		[]string{"incident_key", "channel_id"},
+		[]string{"message_id", "assignee", "assignee_id", "last_notification", "reminder_count"})
+
+	now := time.Now()
+	_, err := c.execOn(ex, query, incidentKey, channelID, messageID, assignee, assigneeID, now, now, 0)
+	if err != nil {
+		return fmt.Errorf("error insertando/actualizando mensaje: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteMessage elimina el registro del mensaje messageID de una incidencia en
+// un canal, junto con los de sus recordatorios. Si el registro ya apunta a otro
+// mensaje (p. ej. uno recién enviado) no cambia nada. Con lease solo se elimina
+// si la instancia sigue siendo líder; si no, devuelve ErrLeaseLost.
+func (c *Client) DeleteMessage(incidentKey, channelID, messageID string, lease Lease) error {
+	var result sql.Result
+	err := c.inTx(func(tx *sql.Tx) error {
+		if err := c.checkLease(tx, lease); err != nil {
+			return err
+		}
+		if _, err := c.execOn(tx, `DELETE FROM discord_reminders WHERE channel_id = ? AND message_id = ?`, channelID, messageID); err != nil {
+			return err
+		}
+		var err error
+		result, err = c.execOn(tx, `DELETE FROM discord_messages WHERE incident_key = ? AND channel_id = ? AND message_id = ?`, incidentKey, channelID, messageID)
+		return err
+	})
+	if err == ErrLeaseLost {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("error eliminando mensaje de BD: %v", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected > 0 {
+		log.Printf("Registro de mensaje eliminado - Incidencia: %s, Canal: %s", incidentKey, channelID)
+	}
+
+	return nil
+}
+
+// ReminderMessages devuelve los recordatorios enviados como respuesta al
+// mensaje (DISCORD_REMINDER_MODE=reply), para borrarlos con él
+func (c *Client) ReminderMessages(channelID, messageID string) ([]string, error) {
+	rows, err := c.query(`SELECT reminder_id FROM discord_reminders WHERE channel_id = ? AND message_id = ? ORDER BY id`, channelID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando recordatorios de %s: %v", messageID, err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error escaneando recordatorio: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// RekeyAssignee cambia el assignee_id de los mensajes y del historial de una
+// incidencia sin tocar Discord: es el mismo assignee con otro identificador
+// (p. ej. "name:<nombre>" resuelto a accountId)
+func (c *Client) RekeyAssignee(incidentKey, fromAssigneeID, toAssigneeID string) error {
+	return c.inTx(func(tx *sql.Tx) error {
+		for _, table := range []string{"discord_messages", "evaluation_history"} {
+			if _, err := c.execOn(tx, `UPDATE `+table+` SET assignee_id = ? WHERE incident_key = ? AND assignee_id = ?`,
+				toAssigneeID, incidentKey, fromAssigneeID); err != nil {
+				return fmt.Errorf("error cambiando assignee_id de %s en %s: %v", incidentKey, table, err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetAllActiveMessages obtiene todos los mensajes activos en Discord
+func (c *Client) GetAllActiveMessages() ([]MessageToDelete, error) {
+	query := `SELECT id, incident_key, channel_id, message_id, assignee, assignee_id, created_at, last_notification, reminder_count FROM discord_messages`
+
+	rows, err := c.query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando mensajes activos: %v", err)
+	}
+	defer rows.Close()
+
+	var messages []MessageToDelete
+	for rows.Next() {
+		var msg MessageToDelete
+		err := rows.Scan(&msg.ID, &msg.IncidentKey, &msg.ChannelID, &msg.MessageID,
+			&msg.Assignee, &msg.AssigneeID, &msg.CreatedAt, &msg.LastNotification, &msg.ReminderCount)
+		if err != nil {
+			log.Printf("Error escaneando mensaje: %v", err)
+			continue
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+// GetMessagesByKeys carga todos los mensajes de un conjunto de incidencias en una sola query.
+// Retorna un mapa incident_key → mensajes (uno por canal) para acceso O(1).
+func (c *Client) GetMessagesByKeys(keys []string) (map[string][]*MessageToDelete, error) {
+	result := make(map[string][]*MessageToDelete)
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	placeholders := strings.Repeat("?,", len(keys))
+	placeholders = placeholders[:len(placeholders)-1] // quitar última coma
+
+	query := fmt.Sprintf(
+		`SELECT id, incident_key, channel_id, message_id, assignee, assignee_id, created_at, last_notification, reminder_count
+		 FROM discord_messages WHERE incident_key IN (%s)`, placeholders)
+
+	args := make([]interface{}, len(keys))
+	for i, k := range keys {
+		args[i] = k
+	}
+
+	rows, err := c.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error cargando mensajes por keys: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg MessageToDelete
+		if err := rows.Scan(&msg.ID, &msg.IncidentKey, &msg.ChannelID, &msg.MessageID,
+			&msg.Assignee, &msg.AssigneeID, &msg.CreatedAt, &msg.LastNotification, &msg.ReminderCount); err != nil {
+			log.Printf("Error escaneando mensaje: %v", err)
+			continue
+		}
+		result[msg.IncidentKey] = append(result[msg.IncidentKey], &msg)
+	}
+
+	return result, nil
+}
+
+// CleanupRemovedIncidents devuelve los mensajes de las incidencias que ya no
+// están en Jira (no figuran en currentIncidentKeys), salvo los que ya tienen un
+// borrado pendiente en el outbox. No borra nada: el llamador los borra por el
+// outbox, que quita cada registro al confirmar el borrado en Discord.
+func (c *Client) CleanupRemovedIncidents(currentIncidentKeys []string) ([]MessageToDelete, error) {
+	activeMessages, err := c.GetAllActiveMessages()
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo mensajes activos: %v", err)
+	}
+
+	current := make(map[string]bool, len(currentIncidentKeys))
+	for _, key := range currentIncidentKeys {
+		current[key] = true
+	}
+
+	rows, err := c.query(`SELECT message_id FROM discord_outbox WHERE operation = ? AND status = ?`, OutboxDelete, OutboxPending)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando borrados pendientes: %v", err)
+	}
+	deleting := make(map[string]bool)
+	for rows.Next() {
+		var messageID string
+		if err := rows.Scan(&messageID); err == nil {
+			deleting[messageID] = true
+		}
+	}
+	rows.Close()
+
+	var removed []MessageToDelete
+	for _, msg := range activeMessages {
+		if !current[msg.IncidentKey] && !deleting[msg.MessageID] {
+			removed = append(removed, msg)
+		}
+	}
+	return removed, nil
+}
+
+// exec, query y queryRow traducen los marcadores '?' al formato del motor
+
+func (c *Client) exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.execOn(c.db, query, args...)
+}
+
+// execer es la conexión (*sql.DB) o una transacción (*sql.Tx)
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func (c *Client) execOn(ex execer, query string, args ...interface{}) (sql.Result, error) {
+	return ex.Exec(c.dialect.rebind(query), args...)
+}
+
+// inTx ejecuta fn en una transacción: confirma si fn no falla y si no la
+// revierte. Dentro de fn solo debe usarse tx (en SQLite hay una sola conexión).
+func (c *Client) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error iniciando transacción: %v", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error confirmando transacción: %v", err)
+	}
+	return nil
+}
+
+func (c *Client) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.db.Query(c.dialect.rebind(query), args...)
+}
+
+func (c *Client) queryRow(query string, args ...interface{}) *sql.Row {
+	return c.db.QueryRow(c.dialect.rebind(query), args...)
+}
+
+// Close cierra la conexión con la base de datos
+func (c *Client) Close() error {
+	if c.db != nil {
+		return c.db.Close()
+	}
+	return nil
+}
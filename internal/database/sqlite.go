@@ -0,0 +1,61 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/PhelGc/furina-sync/internal/config"
+	_ "modernc.org/sqlite"
+)
+
+// Sin bloqueo de migraciones: el proceso usa una sola conexión, y con SQLite
+// solo debe correr una instancia sobre el mismo archivo
+var sqliteDialect = &dialect{
+	name: config.DriverSQLite,
+	migrationsTable: `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER  NOT NULL PRIMARY KEY,
+		name       TEXT     NOT NULL,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`,
+	columnQuery:  `SELECT "notnull" = 0 FROM pragma_table_info(?) WHERE name = ?`,
+	indexQuery:   `SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND name = ?`,
+	upsertClause: onConflictUpdate,
+}
+
+// openSQLite abre (o crea) la base SQLite en config.Path
+func openSQLite(config *Config) (*sql.DB, error) {
+	path := config.Path
+	if path != ":memory:" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("error creando directorio de %s: %v", path, err)
+		}
+	}
+
+	// busy_timeout evita errores "database is locked" con escrituras concurrentes;
+	// _time_format guarda las fechas en un formato que SQLite y el driver entienden
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_time_format", "sqlite")
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("error abriendo SQLite: %v", err)
+	}
+
+	// Una sola conexión: SQLite serializa las escrituras de todos modos, y una
+	// base en memoria solo existe dentro de su conexión
+	db.SetMaxOpenConns(1)
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error abriendo SQLite %s: %v", path, err)
+	}
+
+	log.Printf("Base SQLite abierta: %s", path)
+	return db, nil
+}
@@ -1,25 +1,45 @@
 package discord
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/PhelGc/furina-sync/internal/config"
 	"github.com/PhelGc/furina-sync/internal/evaluator"
 	"github.com/bwmarrin/discordgo"
 )
 
 type Client struct {
-	session *discordgo.Session
-	config  *Config
+	session    *discordgo.Session
+	config     *Config
+	router     *Router
+	mu         sync.RWMutex // protege router y config ante recargas en caliente
+	dmChannels sync.Map     // usuario de Discord → canal de mensaje directo ya abierto
+	botID      string       // usuario del bot, se consulta la primera vez que hace falta
 }
 
 type Config struct {
-	BotToken    string
-	GuildID     string
-	Channels    map[string]string // Map de assignee -> channel ID
-	JiraBaseURL string            // URL base de Jira para construir links a incidencias
+	BotToken        string
+	GuildID         string
+	Channels        map[string]string // Map de accountId -> channel ID
+	RoutesFile      string            // reglas de ruteo en JSON (opcional, ver Router)
+	JiraBaseURL     string            // URL base de Jira para construir links a incidencias
+	FallbackChannel string            // canal para assignees sin canal propio
+	Users           map[string]string // Map de accountId -> ID de usuario de Discord
+	UnmappedPolicy  string            // ver config.UnmappedDM / UnmappedFallback / UnmappedSkip
 }
 
+// ErrNoDestination indica que la incidencia no tiene canal de destino según las
+// reglas de ruteo y la política configurada. No es un fallo de Discord: reintentar no sirve hasta
+// que cambie la configuración.
+var ErrNoDestination = errors.New("sin canal de destino")
+
 // Incident contiene la información de la incidencia que se muestra en el embed
 type Incident struct {
 	Key         string
@@ -27,8 +47,14 @@ type Incident struct {
 	Status      string
 	IssueType   string
 	Assignee    string
+	AssigneeID  string
 	CreatedDate string
 	UpdatedDate string
+	Priority    string
+	Components  []string
+	Labels      []string
+	Sprint      string
+	Parent      string
 }
 
 func NewClient(config *Config) (*Client, error) {
@@ -37,23 +63,41 @@ func NewClient(config *Config) (*Client, error) {
 		return nil, fmt.Errorf("error creando sesión Discord: %v", err)
 	}
 
+	router, err := LoadRouter(config.RoutesFile, config.Channels)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Client{
 		session: session,
 		config:  config,
+		router:  router,
 	}, nil
 }
 
-// SendEvaluationResult envía el resultado de la evaluación IA al canal del assignee.
-// Devuelve el ID del mensaje enviado para poder borrarlo en ciclos futuros.
-func (c *Client) SendEvaluationResult(incident *Incident, eval *evaluator.EvaluationResult) (string, error) {
-	channelID, exists := c.config.Channels[incident.Assignee]
-	if !exists {
-		return "", fmt.Errorf("no se encontró canal para assignee: %s", incident.Assignee)
+// EvaluationPayload arma el embed de la evaluación y lo devuelve en JSON, para
+// guardarlo en el outbox y enviarlo después con SendPayload. ref va en el pie
+// del embed y permite reconocer el mensaje si el envío se reintenta (FindByRef).
+func (c *Client) EvaluationPayload(incident *Incident, eval *evaluator.EvaluationResult, ref string) (string, error) {
+	embed := c.buildEvaluationEmbed(incident, eval)
+	embed.Footer.Text += refSeparator + ref
+
+	data, err := json.Marshal(embed)
+	if err != nil {
+		return "", fmt.Errorf("error serializando evaluación de %s: %v", incident.Key, err)
 	}
+	return string(data), nil
+}
 
-	embed := c.buildEvaluationEmbed(incident, eval)
+// SendPayload envía a un canal un embed armado con EvaluationPayload. Devuelve
+// el ID del mensaje enviado para poder borrarlo en ciclos futuros.
+func (c *Client) SendPayload(channelID, payload string) (string, error) {
+	var embed discordgo.MessageEmbed
+	if err := json.Unmarshal([]byte(payload), &embed); err != nil {
+		return "", fmt.Errorf("embed inválido: %v", err)
+	}
 
-	message, err := c.session.ChannelMessageSendEmbed(channelID, embed)
+	message, err := c.session.ChannelMessageSendEmbed(channelID, &embed)
 	if err != nil {
 		return "", fmt.Errorf("error enviando evaluación a Discord: %v", err)
 	}
@@ -61,75 +105,424 @@ func (c *Client) SendEvaluationResult(incident *Incident, eval *evaluator.Evalua
 	return message.ID, nil
 }
 
-// GetChannelForAssignee obtiene el canal de Discord para un assignee específico
-func (c *Client) GetChannelForAssignee(assignee string) (string, bool) {
-	channelID, exists := c.config.Channels[assignee]
-	return channelID, exists
+// El pie de los embeds identifica los mensajes del bot y, desde el
+// outbox, la operación que los envió
+const (
+	evaluationFooter = "Furina Sync — Evaluación IA"
+	reminderFooter   = "Furina Sync — Recordatorio"
+	noticeFooter     = "Furina Sync — Aviso"
+	refSeparator     = " · ref "
+)
+
+// reminderMessage es el payload de un recordatorio en el outbox
+type reminderMessage struct {
+	Content string                  `json:"content"`
+	Embed   *discordgo.MessageEmbed `json:"embed"`
+	ReplyTo string                  `json:"reply_to,omitempty"`
 }
 
-// DeleteMessage borra un mensaje específico
+// ReminderPayload arma un recordatorio de una evaluación con puntaje bajo y lo
+// devuelve en JSON, para enviarlo después con SendReminder. Con replyTo es una
+// respuesta breve a ese mensaje; sin replyTo, la evaluación completa para volver
+// a publicarla. En los dos casos menciona al assignee si tiene usuario de
+// Discord vinculado (DISCORD_USERS). count es el número de recordatorio y max
+// el límite (0 = sin límite).
+func (c *Client) ReminderPayload(incident *Incident, eval *evaluator.EvaluationResult, replyTo string, count, max int, ref string) (string, error) {
+	c.mu.RLock()
+	userID := c.config.Users[incident.AssigneeID]
+	c.mu.RUnlock()
+
+	who := "**" + incident.Assignee + "**"
+	if incident.Assignee == "" {
+		who = "**sin asignar**"
+	}
+	if userID != "" {
+		who = "<@" + userID + ">"
+	}
+	progress := fmt.Sprintf("recordatorio %d", count)
+	if max > 0 {
+		progress = fmt.Sprintf("recordatorio %d de %d", count, max)
+	}
+
+	msg := reminderMessage{
+		Content: fmt.Sprintf("🔔 %s, **%s** sigue sin cambios con puntaje %d/100 (umbral %d) · %s",
+			who, incident.Key, eval.AverageScore(), eval.Threshold, progress),
+		ReplyTo: replyTo,
+	}
+	if replyTo != "" {
+		msg.Embed = &discordgo.MessageEmbed{
+			Title:  fmt.Sprintf("%s — %s", incident.Key, incident.Title),
+			URL:    c.config.JiraBaseURL + "/browse/" + incident.Key,
+			Color:  0xE74C3C,
+			Footer: &discordgo.MessageEmbedFooter{Text: reminderFooter + refSeparator + ref},
+		}
+	} else {
+		msg.Embed = c.buildEvaluationEmbed(incident, eval)
+		msg.Embed.Footer.Text += refSeparator + ref
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("error serializando recordatorio de %s: %v", incident.Key, err)
+	}
+	return string(data), nil
+}
+
+// SendReminder envía un recordatorio armado con ReminderPayload y devuelve el ID
+// del mensaje. Si el mensaje al que responde ya no existe se envía sin respuesta.
+func (c *Client) SendReminder(channelID, payload string) (string, error) {
+	var msg reminderMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		return "", fmt.Errorf("recordatorio inválido: %v", err)
+	}
+
+	send := &discordgo.MessageSend{
+		Content: msg.Content,
+		Embeds:  []*discordgo.MessageEmbed{msg.Embed},
+		// Solo se notifica a los usuarios mencionados, nunca a roles ni @everyone
+		AllowedMentions: &discordgo.MessageAllowedMentions{Parse: []discordgo.AllowedMentionType{discordgo.AllowedMentionTypeUsers}},
+	}
+	if msg.ReplyTo != "" {
+		failIfNotExists := false
+		send.Reference = &discordgo.MessageReference{MessageID: msg.ReplyTo, ChannelID: channelID, FailIfNotExists: &failIfNotExists}
+	}
+
+	message, err := c.session.ChannelMessageSendComplex(channelID, send)
+	if err != nil {
+		return "", fmt.Errorf("error enviando recordatorio a Discord: %v", err)
+	}
+	return message.ID, nil
+}
+
+// messagePageSize es el máximo de mensajes por consulta que acepta Discord
+const messagePageSize = 100
+
+// clockSkew es el margen que se resta a las horas locales al compararlas con
+// las de Discord
+const clockSkew = 5 * time.Minute
+
+// PostedMessage es un mensaje enviado por el bot
+type PostedMessage struct {
+	ID         string
+	Evaluation bool   // embed de evaluación; no lo son los recordatorios ni los avisos de reasignación
+	Ref        string // ref del outbox; vacío en mensajes anteriores al outbox
+	Timestamp  time.Time
+}
+
+// BotMessages lista los mensajes enviados por el bot al canal: la página más
+// reciente y, hacia atrás, todos los enviados desde since. Un canal borrado o
+// inexistente no tiene mensajes; otros errores (permisos, red) se devuelven
+// para no tomar una decisión con información incompleta.
+func (c *Client) BotMessages(channelID string, since time.Time) ([]PostedMessage, error) {
+	botID, err := c.botUserID()
+	if err != nil {
+		return nil, err
+	}
+
+	var posted []PostedMessage
+	scanned := 0
+	err = c.scanMessages(channelID, time.Time{}, func(message *discordgo.Message) bool {
+		scanned++
+		if scanned > messagePageSize && message.Timestamp.Before(since) {
+			return false
+		}
+		if message.Author == nil || message.Author.ID != botID {
+			return true
+		}
+		entry := PostedMessage{ID: message.ID, Timestamp: message.Timestamp}
+		for _, embed := range message.Embeds {
+			if embed.Footer != nil && strings.HasPrefix(embed.Footer.Text, evaluationFooter) {
+				entry.Evaluation = true
+				_, entry.Ref, _ = strings.Cut(embed.Footer.Text, refSeparator)
+				break
+			}
+		}
+		posted = append(posted, entry)
+		return true
+	})
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownChannel {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo mensajes del canal %s: %v", channelID, err)
+	}
+	return posted, nil
+}
+
+// ConfiguredChannels devuelve los canales que pueden recibir evaluaciones según
+// la configuración vigente: los de las reglas de ruteo (incluidas las de
+// DISCORD_CHANNELS) y el canal de respaldo. No incluye mensajes directos.
+func (c *Client) ConfiguredChannels() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var channels []string
+	add := func(channelID string) {
+		if channelID != "" && !seen[channelID] {
+			seen[channelID] = true
+			channels = append(channels, channelID)
+		}
+	}
+	for _, rule := range c.router.Rules {
+		for _, channelID := range rule.Channels {
+			add(channelID)
+		}
+	}
+	add(c.config.FallbackChannel)
+	return channels
+}
+
+// botUserID devuelve el ID de usuario del bot
+func (c *Client) botUserID() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.botID == "" {
+		user, err := c.session.User("@me")
+		if err != nil {
+			return "", fmt.Errorf("error consultando el usuario del bot: %v", err)
+		}
+		c.botID = user.ID
+	}
+	return c.botID, nil
+}
+
+// FindByRef busca un mensaje cuyo embed lleve ref en el pie entre los enviados
+// al canal desde since (cuando se registró la operación), página por página.
+// Devuelve "" si no lo encuentra.
+func (c *Client) FindByRef(channelID, ref string, since time.Time) (string, error) {
+	found := ""
+	err := c.scanMessages(channelID, since.Add(-clockSkew), func(message *discordgo.Message) bool {
+		for _, embed := range message.Embeds {
+			if embed.Footer != nil && strings.HasSuffix(embed.Footer.Text, refSeparator+ref) {
+				found = message.ID
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("error leyendo mensajes del canal %s: %v", channelID, err)
+	}
+	return found, nil
+}
+
+// scanMessages recorre los mensajes del canal del más nuevo al más viejo, de a
+// messagePageSize, hasta llegar a uno anterior a since o hasta que visit
+// devuelva false
+func (c *Client) scanMessages(channelID string, since time.Time, visit func(*discordgo.Message) bool) error {
+	before := ""
+	for {
+		messages, err := c.session.ChannelMessages(channelID, messagePageSize, before, "", "")
+		if err != nil {
+			return err
+		}
+		for _, message := range messages {
+			if message.Timestamp.Before(since) || !visit(message) {
+				return nil
+			}
+		}
+		if len(messages) < messagePageSize {
+			return nil
+		}
+		before = messages[len(messages)-1].ID
+	}
+}
+
+// Destinations devuelve los canales a los que se envía la evaluación: los que
+// indiquen las reglas de ruteo; si ninguna coincide, según la política para
+// assignees sin canal, un mensaje directo al usuario vinculado o el canal de
+// respaldo. eval puede ser nil (las reglas por puntaje no coinciden).
+func (c *Client) Destinations(incident *Incident, eval *evaluator.EvaluationResult) ([]string, error) {
+	c.mu.RLock()
+	channels := c.router.Route(incident, eval)
+	policy := c.config.UnmappedPolicy
+	fallback := c.config.FallbackChannel
+	userID := c.config.Users[incident.AssigneeID]
+	c.mu.RUnlock()
+
+	if len(channels) > 0 {
+		return channels, nil
+	}
+
+	if policy == config.UnmappedDM && incident.AssigneeID != "" && userID != "" {
+		channelID, err := c.dmChannel(userID)
+		if err == nil {
+			return []string{channelID}, nil
+		}
+		log.Printf("Advertencia: no se pudo abrir mensaje directo con %s, se usa el canal de respaldo: %v", incident.Assignee, err)
+	}
+	if policy != config.UnmappedSkip && fallback != "" {
+		return []string{fallback}, nil
+	}
+
+	assignee := incident.Assignee
+	if assignee == "" {
+		assignee = "sin asignar"
+	}
+	return nil, fmt.Errorf("%w para assignee: %s", ErrNoDestination, assignee)
+}
+
+// dmChannel abre (o reutiliza) el canal de mensaje directo con un usuario
+func (c *Client) dmChannel(userID string) (string, error) {
+	if channelID, ok := c.dmChannels.Load(userID); ok {
+		return channelID.(string), nil
+	}
+	dm, err := c.session.UserChannelCreate(userID)
+	if err != nil {
+		return "", err
+	}
+	c.dmChannels.Store(userID, dm.ID)
+	return dm.ID, nil
+}
+
+// Channels devuelve una copia del mapa assignee → canal vigente
+func (c *Client) Channels() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	channels := make(map[string]string, len(c.config.Channels))
+	for assignee, channelID := range c.config.Channels {
+		channels[assignee] = channelID
+	}
+	return channels
+}
+
+// Router devuelve las reglas de ruteo vigentes
+func (c *Client) Router() *Router {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.router
+}
+
+// SetRouting reemplaza el mapa assignee → canal y las reglas de ruteo de forma
+// atómica. El router debe haberse construido con LoadRouter a partir de channels.
+func (c *Client) SetRouting(channels map[string]string, router *Router) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config.Channels = channels
+	c.router = router
+}
+
+// SetUnmapped reemplaza la política para assignees sin canal, el canal de
+// respaldo y el mapa accountId → usuario de Discord
+func (c *Client) SetUnmapped(policy, fallbackChannel string, users map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config.UnmappedPolicy = policy
+	c.config.FallbackChannel = fallbackChannel
+	c.config.Users = users
+}
+
+// DeleteMessage borra un mensaje específico. Un mensaje que ya no existe (lo
+// borró alguien a mano, o un intento anterior) no es un error.
 func (c *Client) DeleteMessage(channelID, messageID string) error {
 	err := c.session.ChannelMessageDelete(channelID, messageID)
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownMessage {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("error borrando mensaje de Discord: %v", err)
 	}
 	return nil
 }
 
+// Notice es un aviso informativo para un canal, p. ej. de una reasignación
+type Notice struct {
+	ChannelID string
+	Text      string
+}
+
+// HandoverNotices arma los avisos de una reasignación para los canales del
+// assignee anterior y para los del nuevo. Se envían por el outbox con
+// NoticePayload y no se registran en discord_messages.
+func (c *Client) HandoverNotices(incident *Incident, fromName string, fromChannels []string) []Notice {
+	if fromName == "" {
+		fromName = "sin asignar"
+	}
+	toName := incident.Assignee
+	if toName == "" {
+		toName = "sin asignar"
+	}
+
+	var notices []Notice
+	notified := make(map[string]bool)
+	for _, channelID := range fromChannels {
+		if notified[channelID] {
+			continue
+		}
+		notified[channelID] = true
+		notices = append(notices, Notice{ChannelID: channelID, Text: fmt.Sprintf("🔀 **%s** fue reasignada a %s.", incident.Key, toName)})
+	}
+	// Sin evaluación las reglas por puntaje no coinciden: el aviso va a los canales fijos
+	toChannels, _ := c.Destinations(incident, nil)
+	for _, channelID := range toChannels {
+		if notified[channelID] {
+			continue
+		}
+		notices = append(notices, Notice{ChannelID: channelID, Text: fmt.Sprintf("🔀 **%s** recibida de %s.", incident.Key, fromName)})
+	}
+	return notices
+}
+
+// NoticePayload arma el embed de un aviso y lo devuelve en JSON, para enviarlo
+// con SendPayload. Como en las evaluaciones, ref va en el pie.
+func (c *Client) NoticePayload(text, ref string) (string, error) {
+	embed := &discordgo.MessageEmbed{
+		Description: text,
+		Color:       0x3498DB,
+		Footer:      &discordgo.MessageEmbedFooter{Text: noticeFooter + refSeparator + ref},
+	}
+	data, err := json.Marshal(embed)
+	if err != nil {
+		return "", fmt.Errorf("error serializando aviso: %v", err)
+	}
+	return string(data), nil
+}
+
 // buildEvaluationEmbed construye el embed con el resultado de la evaluación IA
 func (c *Client) buildEvaluationEmbed(incident *Incident, eval *evaluator.EvaluationResult) *discordgo.MessageEmbed {
-	boolIcon := func(b bool) string {
-		if b {
-			return "✅"
+	// Color según puntaje promedio de las fases ejecutadas:
+	// rojo bajo el umbral del set, verde desde umbral+20, amarillo entre ambos
+	avgScore := eval.AverageScore()
+	color := 0xE74C3C
+	if avgScore >= eval.Threshold+20 {
+		color = 0x2ECC71
+	} else if avgScore >= eval.Threshold {
+		color = 0xF39C12
+	}
+
+	// Metadatos de la incidencia en una fila de campos cortos
+	var fields []*discordgo.MessageEmbedField
+	for _, meta := range []struct{ name, value string }{
+		{"Tipo", incident.IssueType},
+		{"Prioridad", incident.Priority},
+		{"Sprint", incident.Sprint},
+		{"Componentes", strings.Join(incident.Components, ", ")},
+		{"Etiquetas", strings.Join(incident.Labels, ", ")},
+		{"Padre", incident.Parent},
+	} {
+		if meta.value != "" {
+			fields = append(fields, &discordgo.MessageEmbedField{Name: meta.name, Value: truncate(meta.value, 1024), Inline: true})
 		}
-		return "❌"
 	}
 
-	// Color según puntaje promedio de ambas fases disponibles
-	avgScore := eval.Phase1.Puntaje
-	if eval.Phase2 != nil {
-		avgScore = (eval.Phase1.Puntaje + eval.Phase2.Puntaje) / 2
-	}
-	color := 0xE74C3C // Rojo < 60
-	if avgScore >= 80 {
-		color = 0x2ECC71 // Verde
-	} else if avgScore >= 60 {
-		color = 0xF39C12 // Amarillo
-	}
-
-	// Campo de evaluación de descripción (Fase 1)
-	p1Value := fmt.Sprintf(
-		"**%d/100** · Claridad: %s · Causa raíz: %s · Impacto: %s",
-		eval.Phase1.Puntaje,
-		eval.Phase1.Claridad,
-		eval.Phase1.CausaRaiz,
-		boolIcon(eval.Phase1.ImpactoDefinido),
-	)
-
-	fields := []*discordgo.MessageEmbedField{
-		{Name: "Descripción", Value: p1Value, Inline: false},
-		{Name: "Obs. Descripción", Value: truncate(eval.Phase1.Observaciones, 1024), Inline: false},
-	}
-
-	// Campo de evaluación de conclusión (Fase 2) si está disponible
-	if eval.Phase2 != nil {
-		p2Value := fmt.Sprintf(
-			"**%d/100** · Coherencia: %s · Acciones: %s · Responsables: %s",
-			eval.Phase2.Puntaje,
-			boolIcon(eval.Phase2.CoherenciaConDesc),
-			boolIcon(eval.Phase2.AccionesDefinidas),
-			boolIcon(eval.Phase2.ResponsablesAsig),
-		)
+	// Un campo de resumen por fase, más sus observaciones si se ejecutó
+	for _, phase := range eval.Phases {
+		if !phase.Scored() {
+			fields = append(fields, &discordgo.MessageEmbedField{Name: phase.Label, Value: phase.Skipped, Inline: false})
+			continue
+		}
+
+		summary := []string{fmt.Sprintf("**%d/100**", phase.Score)}
+		for _, out := range phase.Outputs {
+			summary = append(summary, fmt.Sprintf("%s: %s", out.Label, formatOutput(out)))
+		}
 		fields = append(fields,
-			&discordgo.MessageEmbedField{Name: "Conclusión", Value: p2Value, Inline: false},
-			&discordgo.MessageEmbedField{Name: "Obs. Conclusión", Value: truncate(eval.Phase2.Observaciones, 1024), Inline: false},
+			&discordgo.MessageEmbedField{Name: phase.Label, Value: truncate(strings.Join(summary, " · "), 1024), Inline: false},
+			&discordgo.MessageEmbedField{Name: "Obs. " + phase.Label, Value: truncate(phase.Notes, 1024), Inline: false},
 		)
-	} else {
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:   "Conclusión",
-			Value:  "Sin conclusión — evaluación pendiente",
-			Inline: false,
-		})
 	}
 
 	return &discordgo.MessageEmbed{
@@ -138,7 +531,22 @@ func (c *Client) buildEvaluationEmbed(incident *Incident, eval *evaluator.Evalua
 		Color:     color,
 		Fields:    fields,
 		Timestamp: time.Now().Format(time.RFC3339),
-		Footer:    &discordgo.MessageEmbedFooter{Text: "Furina Sync — Evaluación IA"},
+		Footer:    &discordgo.MessageEmbedFooter{Text: evaluationFooter},
+	}
+}
+
+// formatOutput muestra un campo de salida: íconos para booleanos, texto para el resto
+func formatOutput(out evaluator.OutputValue) string {
+	switch v := out.Value.(type) {
+	case bool:
+		if v {
+			return "✅"
+		}
+		return "❌"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
 	}
 }
 
@@ -0,0 +1,224 @@
+package discord
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// digestFooter identifica los resúmenes; no empieza como evaluationFooter, así
+// que la reconciliación no los toma por evaluaciones huérfanas
+const digestFooter = "Furina Sync — Resumen"
+
+// NoScore marca un promedio sin evaluaciones en Digest
+const NoScore = -1
+
+// Digest es un resumen periódico de evaluaciones (ver internal/report)
+type Digest struct {
+	Title       string
+	From, To    time.Time // período cubierto, en la zona horaria del calendario
+	PhaseLabels [2]string // nombres de la fase 1 y 2
+	Average     int       // puntaje promedio del período
+	Previous    int       // puntaje promedio del período anterior (NoScore = sin evaluaciones)
+	Incidents   []DigestIncident
+	Assignees   []DigestAssignee
+	Worst       []DigestIncident // peores puntajes, del más bajo al más alto
+}
+
+// DigestIncident es la última evaluación de una incidencia en el período
+type DigestIncident struct {
+	Key      string
+	Title    string
+	Assignee string
+	Score    int
+}
+
+// DigestAssignee son los promedios de un assignee en el período
+type DigestAssignee struct {
+	Name      string
+	Incidents int
+	Phases    [2]int // promedio de la fase 1 y 2 (NoScore = sin puntaje)
+	Average   int
+	Previous  int // promedio del período anterior (NoScore = sin evaluaciones)
+}
+
+// Un embed admite 1024 caracteres por campo y 6000 en total: la lista de
+// assignees ocupa como mucho tres campos
+const (
+	digestFieldLimit     = 1024
+	digestAssigneeFields = 3
+)
+
+// digestMessage es el payload de un resumen o un ranking en el outbox
+type digestMessage struct {
+	Embed *discordgo.MessageEmbed `json:"embed"`
+	Chart []byte                  `json:"chart,omitempty"` // PNG adjunto al ranking
+}
+
+// DigestPayload arma el embed del resumen y lo devuelve en JSON, para enviarlo
+// por el outbox con SendDigestPayload. Como en las evaluaciones, ref va en el pie.
+func (c *Client) DigestPayload(digest *Digest, ref string) (string, error) {
+	return digestPayload(c.buildDigestEmbed(digest), nil, ref)
+}
+
+// SendDigestPayload envía un resumen o un ranking armado con DigestPayload o
+// LeaderboardPayload. Devuelve el ID del mensaje.
+func (c *Client) SendDigestPayload(channelID, payload string) (string, error) {
+	var msg digestMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		return "", fmt.Errorf("resumen inválido: %v", err)
+	}
+	if msg.Embed == nil {
+		return "", fmt.Errorf("resumen inválido: sin embed")
+	}
+	send := &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{msg.Embed}}
+	if len(msg.Chart) > 0 {
+		send.Files = []*discordgo.File{{Name: leaderboardChart, ContentType: "image/png", Reader: bytes.NewReader(msg.Chart)}}
+	}
+
+	message, err := c.session.ChannelMessageSendComplex(channelID, send)
+	if err != nil {
+		return "", fmt.Errorf("error enviando resumen a Discord: %v", err)
+	}
+	return message.ID, nil
+}
+
+func digestPayload(embed *discordgo.MessageEmbed, chart []byte, ref string) (string, error) {
+	embed.Footer.Text += refSeparator + ref
+	data, err := json.Marshal(digestMessage{Embed: embed, Chart: chart})
+	if err != nil {
+		return "", fmt.Errorf("error serializando resumen: %v", err)
+	}
+	return string(data), nil
+}
+
+// buildDigestEmbed arma el embed del resumen: la tendencia en la descripción,
+// un campo por grupo de assignees, los peores puntajes y las incidencias
+// evaluadas. Las listas largas se reparten en varios campos o se recortan.
+func (c *Client) buildDigestEmbed(digest *Digest) *discordgo.MessageEmbed {
+	description := fmt.Sprintf("Del %s al %s", digest.From.Format("02/01 15:04"), digest.To.Format("02/01 15:04"))
+	if len(digest.Incidents) == 0 {
+		description += "\nSin incidencias evaluadas en el período."
+	} else {
+		description += fmt.Sprintf("\n**%d** incidencia(s) evaluada(s) · promedio **%d/100** %s",
+			len(digest.Incidents), digest.Average, trend(digest.Average, digest.Previous))
+	}
+
+	var fields []*discordgo.MessageEmbedField
+	var lines []string
+	for _, a := range digest.Assignees {
+		lines = append(lines, fmt.Sprintf("**%s** · %d · %s %s · %s %s · prom. %d %s", a.Name, a.Incidents,
+			digest.PhaseLabels[0], formatScore(a.Phases[0]), digest.PhaseLabels[1], formatScore(a.Phases[1]),
+			a.Average, trend(a.Average, a.Previous)))
+	}
+	fields = append(fields, listFields("Por assignee", lines, digestAssigneeFields)...)
+
+	if len(digest.Worst) > 0 {
+		lines = lines[:0]
+		for _, inc := range digest.Worst {
+			lines = append(lines, fmt.Sprintf("**%d** · %s — %s (%s)", inc.Score, c.issueLink(inc.Key), Shorten(inc.Title, 60), inc.Assignee))
+		}
+		fields = append(fields, listFields("Peores puntajes", lines, 1)...)
+	}
+
+	if len(digest.Incidents) > 0 {
+		lines = lines[:0]
+		for _, inc := range digest.Incidents {
+			lines = append(lines, c.issueLink(inc.Key))
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Evaluadas", Value: joinLimited(lines, " · ", digestFieldLimit)})
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       digest.Title,
+		Description: description,
+		Color:       0x3498DB,
+		Fields:      fields,
+		Timestamp:   digest.To.Format(time.RFC3339),
+		Footer:      &discordgo.MessageEmbedFooter{Text: digestFooter},
+	}
+}
+
+// issueLink es un link Markdown a la incidencia en Jira
+func (c *Client) issueLink(key string) string {
+	return fmt.Sprintf("[%s](%s/browse/%s)", key, c.config.JiraBaseURL, key)
+}
+
+// listFields reparte las líneas en campos de hasta 1024 caracteres, como mucho
+// max campos; lo que no entra se resume en la última línea
+func listFields(name string, lines []string, max int) []*discordgo.MessageEmbedField {
+	var fields []*discordgo.MessageEmbedField
+	for len(lines) > 0 && len(fields) < max {
+		last := len(fields) == max-1
+		value, used := "", 0
+		for _, line := range lines {
+			if len(value)+len(line)+1 > digestFieldLimit-40 && used > 0 {
+				break
+			}
+			value += line + "\n"
+			used++
+		}
+		lines = lines[used:]
+		if last && len(lines) > 0 {
+			value += fmt.Sprintf("… y %d más", len(lines))
+			lines = nil
+		}
+		fieldName := name
+		if len(fields) > 0 {
+			fieldName = name + " (cont.)"
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{Name: fieldName, Value: truncate(strings.TrimSpace(value), digestFieldLimit)})
+	}
+	return fields
+}
+
+// joinLimited une los valores hasta limit caracteres e indica cuántos quedaron fuera
+func joinLimited(values []string, sep string, limit int) string {
+	result := ""
+	for i, value := range values {
+		next := result
+		if i > 0 {
+			next += sep
+		}
+		next += value
+		if len(next) > limit-20 {
+			return result + fmt.Sprintf("… y %d más", len(values)-i)
+		}
+		result = next
+	}
+	return result
+}
+
+// Shorten corta el texto a max caracteres (no bytes) y marca el corte con …
+func Shorten(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+func formatScore(score int) string {
+	if score == NoScore {
+		return "—"
+	}
+	return fmt.Sprint(score)
+}
+
+// trend compara un promedio con el del período anterior
+func trend(current, previous int) string {
+	switch {
+	case previous == NoScore:
+		return ""
+	case current > previous:
+		return fmt.Sprintf("▲ +%d", current-previous)
+	case current < previous:
+		return fmt.Sprintf("▼ %d", current-previous)
+	default:
+		return "= 0"
+	}
+}
@@ -0,0 +1,142 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// leaderboardChart es el nombre del PNG adjunto al ranking
+const leaderboardChart = "tendencia.png"
+
+// Leaderboard es un ranking de calidad por equipo y por assignee (ver internal/report)
+type Leaderboard struct {
+	Title         string
+	From, To      time.Time // período cubierto, en la zona horaria del calendario
+	Percentile    int       // percentil mostrado en las filas (p. ej. 90)
+	ShareLabel    string    // campo booleano cuya proporción se muestra (p. ej. Impacto)
+	Teams         []LeaderboardRow
+	Assignees     []LeaderboardRow // ordenados del mejor al peor promedio
+	Distributions []Distribution   // campos categóricos, p. ej. Claridad y Causa raíz
+}
+
+// LeaderboardRow son las estadísticas de puntaje de un equipo o un assignee
+type LeaderboardRow struct {
+	Name       string
+	Incidents  int
+	Mean       int
+	Median     int
+	Percentile int
+	Share      int // % de incidencias con el campo booleano en true (NoScore = sin datos)
+}
+
+// Distribution es el reparto de los valores de un campo categórico por equipo
+// y por assignee
+type Distribution struct {
+	Label     string
+	Rows      []DistributionRow // por equipo
+	Assignees []DistributionRow // por assignee, por nombre
+}
+
+// DistributionRow es el reparto de un equipo o un assignee, de mayor a menor
+type DistributionRow struct {
+	Name   string
+	Values []ValueCount
+}
+
+// ValueCount es cuántas incidencias tienen un valor
+type ValueCount struct {
+	Value string
+	Count int
+}
+
+// LeaderboardPayload arma el embed del ranking con el gráfico de tendencia
+// adjunto (PNG) y lo devuelve en JSON, para enviarlo con SendDigestPayload. Sin
+// gráfico se publica solo el embed.
+func (c *Client) LeaderboardPayload(board *Leaderboard, chart []byte, ref string) (string, error) {
+	embed := c.buildLeaderboardEmbed(board)
+	if len(chart) > 0 {
+		embed.Image = &discordgo.MessageEmbedImage{URL: "attachment://" + leaderboardChart}
+	}
+	return digestPayload(embed, chart, ref)
+}
+
+// buildLeaderboardEmbed arma el embed del ranking: una fila por equipo, el
+// ranking de assignees y un campo por distribución
+func (c *Client) buildLeaderboardEmbed(board *Leaderboard) *discordgo.MessageEmbed {
+	description := fmt.Sprintf("Del %s al %s", board.From.Format("02/01 15:04"), board.To.Format("02/01 15:04"))
+	if len(board.Assignees) == 0 {
+		description += "\nSin incidencias evaluadas en el período."
+	} else {
+		description += fmt.Sprintf("\nPuntaje: media · mediana · p%d", board.Percentile)
+	}
+
+	var fields []*discordgo.MessageEmbedField
+	var lines []string
+	for _, row := range board.Teams {
+		lines = append(lines, fmt.Sprintf("**%s** · %s", row.Name, formatRow(board, row)))
+	}
+	fields = append(fields, listFields("Equipos", lines, 1)...)
+
+	lines = lines[:0]
+	for i, row := range board.Assignees {
+		lines = append(lines, fmt.Sprintf("%d. **%s** · %s", i+1, row.Name, formatRow(board, row)))
+	}
+	fields = append(fields, listFields("Assignees", lines, 2)...)
+
+	// Un campo por distribución: primero los equipos y después los assignees,
+	// que son los que se recortan si no entran
+	for _, dist := range board.Distributions {
+		lines = lines[:0]
+		for _, row := range dist.Rows {
+			if line := distributionLine(row); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		if len(dist.Assignees) > 0 {
+			lines = append(lines, "*Por assignee*")
+			for _, row := range dist.Assignees {
+				if line := distributionLine(row); line != "" {
+					lines = append(lines, line)
+				}
+			}
+		}
+		fields = append(fields, listFields(dist.Label, lines, 1)...)
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       board.Title,
+		Description: description,
+		Color:       0x9B59B6,
+		Fields:      fields,
+		Timestamp:   board.To.Format(time.RFC3339),
+		Footer:      &discordgo.MessageEmbedFooter{Text: digestFooter},
+	}
+}
+
+// distributionLine muestra el reparto de una fila en porcentajes; "" si no tiene valores
+func distributionLine(row DistributionRow) string {
+	total := 0
+	for _, v := range row.Values {
+		total += v.Count
+	}
+	if total == 0 {
+		return ""
+	}
+	parts := make([]string, len(row.Values))
+	for i, v := range row.Values {
+		parts[i] = fmt.Sprintf("%s %d%%", v.Value, v.Count*100/total)
+	}
+	return fmt.Sprintf("**%s** · %s", row.Name, strings.Join(parts, " · "))
+}
+
+// formatRow muestra las estadísticas de una fila
+func formatRow(board *Leaderboard, row LeaderboardRow) string {
+	text := fmt.Sprintf("%d inc. · %d · %d · %d", row.Incidents, row.Mean, row.Median, row.Percentile)
+	if board.ShareLabel != "" && row.Share != NoScore {
+		text += fmt.Sprintf(" · %s %d%%", board.ShareLabel, row.Share)
+	}
+	return text
+}
@@ -0,0 +1,228 @@
+package discord
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PhelGc/furina-sync/internal/evaluator"
+)
+
+// Modos de evaluación de las reglas de ruteo
+const (
+	RouteFirstMatch = "first" // solo la primera regla que coincide
+	RouteAllMatch   = "all"   // todas las reglas que coinciden (canales sin repetir)
+)
+
+// RoutesConfig archivo de reglas de ruteo (DISCORD_ROUTES_FILE)
+type RoutesConfig struct {
+	Mode  string      `json:"mode"`
+	Rules []RouteRule `json:"rules"`
+}
+
+// RouteRule regla de ruteo. Dentro de cada lista basta con que coincida un
+// valor; entre condiciones distintas deben coincidir todas. Una condición vacía
+// no filtra, así que una regla sin condiciones coincide con todo.
+type RouteRule struct {
+	Name       string   `json:"name"`
+	Assignees  []string `json:"assignees"` // accountId o nombre visible (obsoleto)
+	IssueTypes []string `json:"issue_types"`
+	Projects   []string `json:"projects"` // prefijo de la key (PROJ-123 → PROJ)
+	Components []string `json:"components"`
+	Labels     []string `json:"labels"`
+	MinScore   *int     `json:"min_score"` // puntaje promedio de las fases, inclusive
+	MaxScore   *int     `json:"max_score"`
+	Channels   []string `json:"channels"`
+}
+
+// Router decide a qué canales se envía cada evaluación. Las reglas del archivo
+// se evalúan en orden y después las generadas desde DISCORD_CHANNELS (una por
+// assignee), que actúan como ruteo por defecto.
+type Router struct {
+	Mode  string
+	Rules []RouteRule
+	File  string // archivo de reglas, vacío si solo se usa DISCORD_CHANNELS
+
+	warnedByName *sync.Map // nombres visibles ya advertidos como clave obsoleta
+}
+
+// LoadRouter lee el archivo de reglas (opcional) y agrega las asignaciones de
+// DISCORD_CHANNELS. Valida todo antes de devolver el router.
+func LoadRouter(path string, channels map[string]string) (*Router, error) {
+	router := &Router{Mode: RouteFirstMatch, File: path, warnedByName: &sync.Map{}}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error leyendo reglas de ruteo %s: %v", path, err)
+		}
+		var routes RoutesConfig
+		if err := json.Unmarshal(data, &routes); err != nil {
+			return nil, fmt.Errorf("reglas de ruteo %s: JSON inválido: %w", path, err)
+		}
+		if routes.Mode != "" {
+			router.Mode = strings.ToLower(routes.Mode)
+		}
+		router.Rules = routes.Rules
+	}
+
+	// Orden estable para que el modo first sea determinista
+	assignees := make([]string, 0, len(channels))
+	for assignee := range channels {
+		assignees = append(assignees, assignee)
+	}
+	sort.Strings(assignees)
+	for _, assignee := range assignees {
+		router.Rules = append(router.Rules, RouteRule{
+			Name:      "DISCORD_CHANNELS:" + assignee,
+			Assignees: []string{assignee},
+			Channels:  []string{channels[assignee]},
+		})
+	}
+
+	if err := router.validate(); err != nil {
+		return nil, err
+	}
+	return router, nil
+}
+
+// validate revisa el modo y que cada regla tenga canales válidos
+func (r *Router) validate() error {
+	if r.Mode != RouteFirstMatch && r.Mode != RouteAllMatch {
+		return fmt.Errorf("modo de ruteo inválido: %q (valores: first, all)", r.Mode)
+	}
+	for i, rule := range r.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if len(rule.Channels) == 0 {
+			return fmt.Errorf("la regla de ruteo %s no tiene canales", name)
+		}
+		for _, channelID := range rule.Channels {
+			if !IsSnowflake(channelID) {
+				return fmt.Errorf("canal inválido en la regla %s: %q (debe ser un ID numérico)", name, channelID)
+			}
+		}
+		if rule.MinScore != nil && rule.MaxScore != nil && *rule.MinScore > *rule.MaxScore {
+			return fmt.Errorf("la regla de ruteo %s tiene min_score mayor que max_score", name)
+		}
+	}
+	return nil
+}
+
+// Route devuelve los canales de la incidencia según las reglas. eval puede ser
+// nil (p. ej. avisos de reasignación); en ese caso las reglas con rango de
+// puntaje no coinciden.
+func (r *Router) Route(incident *Incident, eval *evaluator.EvaluationResult) []string {
+	var channels []string
+	seen := make(map[string]bool)
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		if !r.matches(rule, incident, eval) {
+			continue
+		}
+		for _, channelID := range rule.Channels {
+			if !seen[channelID] {
+				seen[channelID] = true
+				channels = append(channels, channelID)
+			}
+		}
+		if r.Mode == RouteFirstMatch {
+			break
+		}
+	}
+	return channels
+}
+
+// matches indica si la incidencia cumple todas las condiciones de la regla
+func (r *Router) matches(rule *RouteRule, incident *Incident, eval *evaluator.EvaluationResult) bool {
+	if len(rule.Assignees) > 0 && !r.matchAssignee(rule, incident) {
+		return false
+	}
+	if len(rule.IssueTypes) > 0 && !containsFold(rule.IssueTypes, incident.IssueType) {
+		return false
+	}
+	if len(rule.Projects) > 0 {
+		project, _, _ := strings.Cut(incident.Key, "-")
+		if !containsFold(rule.Projects, project) {
+			return false
+		}
+	}
+	if len(rule.Components) > 0 && !anyFold(rule.Components, incident.Components) {
+		return false
+	}
+	if len(rule.Labels) > 0 && !anyFold(rule.Labels, incident.Labels) {
+		return false
+	}
+	if rule.MinScore != nil || rule.MaxScore != nil {
+		if eval == nil {
+			return false
+		}
+		score := eval.AverageScore()
+		if rule.MinScore != nil && score < *rule.MinScore {
+			return false
+		}
+		if rule.MaxScore != nil && score > *rule.MaxScore {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAssignee busca el assignee por accountId; si no, acepta el nombre
+// visible como clave obsoleta y lo advierte una sola vez por nombre
+func (r *Router) matchAssignee(rule *RouteRule, incident *Incident) bool {
+	for _, assignee := range rule.Assignees {
+		if incident.AssigneeID != "" && assignee == incident.AssigneeID {
+			return true
+		}
+	}
+	for _, assignee := range rule.Assignees {
+		if incident.Assignee != "" && assignee == incident.Assignee {
+			if _, warned := r.warnedByName.LoadOrStore(assignee, true); !warned {
+				log.Printf("Advertencia: la regla de ruteo %s usa el nombre %q como clave; reemplázalo por su accountId %s",
+					rule.Name, assignee, incident.AssigneeID)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold indica si value está en la lista, sin distinguir mayúsculas
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyFold indica si algún valor de la incidencia está en la lista
+func anyFold(list, values []string) bool {
+	for _, value := range values {
+		if containsFold(list, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSnowflake indica si el valor es un ID numérico de Discord
+func IsSnowflake(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, ch := range id {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
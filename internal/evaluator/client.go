@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/PhelGc/furina-sync/internal/config"
 	"github.com/PhelGc/furina-sync/internal/jira"
 )
 
@@ -16,20 +19,34 @@ const geminiAPIBase = "https://generativelanguage.googleapis.com/v1beta/models"
 
 // Client realiza llamadas a la API de Gemini para evaluar incidencias
 type Client struct {
-	apiKey     string
-	model      string
-	prompts    *PromptLoader
-	httpClient *http.Client
+	apiKey       string
+	model        string
+	hashComments bool
+	prompts      atomic.Pointer[PromptLoader] // reemplazable en caliente con SetPrompts
+	httpClient   *http.Client
 }
 
 // NewClient crea un cliente de evaluación IA usando Gemini
-func NewClient(apiKey, model string, prompts *PromptLoader) *Client {
-	return &Client{
-		apiKey:     apiKey,
-		model:      model,
-		prompts:    prompts,
-		httpClient: &http.Client{Timeout: 45 * time.Second},
+func NewClient(cfg config.EvalConfig, prompts *PromptLoader) *Client {
+	c := &Client{
+		apiKey:       cfg.APIKey,
+		model:        cfg.Model,
+		hashComments: cfg.HashComments,
+		httpClient:   &http.Client{Timeout: 45 * time.Second},
 	}
+	c.prompts.Store(prompts)
+	return c
+}
+
+// SetPrompts reemplaza los prompts de forma atómica. Las evaluaciones en curso
+// terminan con los prompts que tenían al empezar.
+func (c *Client) SetPrompts(prompts *PromptLoader) {
+	c.prompts.Store(prompts)
+}
+
+// Prompts devuelve los prompts vigentes
+func (c *Client) Prompts() *PromptLoader {
+	return c.prompts.Load()
 }
 
 // --- Structs para la API de Gemini ---
@@ -63,43 +80,78 @@ type geminiResponse struct {
 	} `json:"candidates"`
 }
 
-// Evaluate ejecuta las dos fases de evaluación en secuencia.
-// Fase 2 solo se ejecuta si la incidencia tiene conclusión.
+// Evaluate ejecuta en orden las fases del pipeline del set de prompts que
+// corresponde a la incidencia. Una fase que no cumple su condición queda en el
+// resultado como omitida; el error de una fase opcional también la omite, pero
+// el de una fase obligatoria invalida la evaluación.
 func (c *Client) Evaluate(incident *jira.Incident) (*EvaluationResult, error) {
-	result := &EvaluationResult{IncidentKey: incident.Key}
+	// Todas las fases usan el mismo set aunque haya una recarga a mitad de evaluación
+	set, threshold := c.prompts.Load().Resolve(incident)
+	result := &EvaluationResult{
+		IncidentKey:   incident.Key,
+		PromptVersion: set.Version(),
+		PromptSet:     set.Name,
+		Threshold:     threshold,
+	}
 
-	// Fase 1: evaluar título + descripción
-	userMsg1 := fmt.Sprintf("Título: %s\n\nDescripción:\n%s", incident.Title, incident.Description)
-	p1Text, err := c.callAPI(c.prompts.Phase1, userMsg1)
-	if err != nil {
-		return nil, fmt.Errorf("fase 1: %w", err)
-	}
-
-	var p1 Phase1Result
-	if err := json.Unmarshal([]byte(cleanJSON(p1Text)), &p1); err != nil {
-		return nil, fmt.Errorf("fase 1 — JSON inválido (%q): %w", p1Text, err)
-	}
-	result.Phase1 = &p1
-
-	// Fase 2: evaluar conclusión (solo si hay texto suficiente)
-	if strings.TrimSpace(incident.Conclusion) != "" {
-		p1JSON, _ := json.Marshal(p1)
-		userMsg2 := fmt.Sprintf(
-			"Resultado evaluación descripción (Fase 1):\n%s\n\nConclusión de la incidencia:\n%s",
-			string(p1JSON), incident.Conclusion,
-		)
-		p2Text, err := c.callAPI(c.prompts.Phase2, userMsg2)
-		if err == nil {
-			var p2 Phase2Result
-			if err := json.Unmarshal([]byte(cleanJSON(p2Text)), &p2); err == nil {
-				result.Phase2 = &p2
+	results := make(map[string]*PhaseResult)
+	for _, phase := range set.Phases {
+		if !phase.shouldRun(incident, results) {
+			result.Phases = append(result.Phases, &PhaseResult{Name: phase.Name, Label: phase.Label, Skipped: phase.skipMessage()})
+			continue
+		}
+
+		phaseResult, err := c.runPhase(phase, incident, results)
+		if err != nil {
+			if !phase.Optional {
+				return nil, fmt.Errorf("fase %s: %w", phase.Name, err)
 			}
+			log.Printf("[EVAL] Fase opcional %s omitida en %s: %v", phase.Name, incident.Key, err)
+			phaseResult = &PhaseResult{Name: phase.Name, Label: phase.Label, Skipped: "Evaluación no disponible"}
 		}
+		results[phase.Name] = phaseResult
+		result.Phases = append(result.Phases, phaseResult)
 	}
 
 	return result, nil
 }
 
+// Restore reconstruye una evaluación guardada en caché (fases serializadas con
+// PhasesJSON) sin llamar al modelo. El set y el umbral se resuelven con la
+// configuración vigente; la versión es la con que se evaluó originalmente.
+func (c *Client) Restore(incident *jira.Incident, promptVersion, phasesJSON string) (*EvaluationResult, error) {
+	if phasesJSON == "" {
+		return nil, fmt.Errorf("la evaluación en caché de %s no tiene resultados por fase", incident.Key)
+	}
+
+	set, threshold := c.prompts.Load().Resolve(incident)
+	result := &EvaluationResult{
+		IncidentKey:   incident.Key,
+		PromptVersion: promptVersion,
+		PromptSet:     set.Name,
+		Threshold:     threshold,
+	}
+	if err := json.Unmarshal([]byte(phasesJSON), &result.Phases); err != nil {
+		return nil, fmt.Errorf("error parseando evaluación en caché de %s: %v", incident.Key, err)
+	}
+	return result, nil
+}
+
+// runPhase renderiza el prompt de la fase, llama al modelo y valida la respuesta
+func (c *Client) runPhase(phase *Phase, incident *jira.Incident, results map[string]*PhaseResult) (*PhaseResult, error) {
+	system, err := phase.Prompt.Render(PromptData{Incident: incident, Phases: results})
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := c.callAPI(system, phase.userMessage(incident, results))
+	if err != nil {
+		return nil, err
+	}
+
+	return phase.parseOutput(text)
+}
+
 // callAPI envía un mensaje a Gemini y devuelve el texto de respuesta
 func (c *Client) callAPI(systemPrompt, userMessage string) (string, error) {
 	reqBody := geminiRequest{
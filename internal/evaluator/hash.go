@@ -0,0 +1,59 @@
+package evaluator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/PhelGc/furina-sync/internal/jira"
+)
+
+// PromptVersion devuelve la versión vigente del set de prompts que evalúa la incidencia
+func (c *Client) PromptVersion(incident *jira.Incident) string {
+	return c.prompts.Load().Version(incident)
+}
+
+// InputHash calcula un hash de las entradas exactas que usa la evaluación: los
+// campos de la incidencia que declaran las fases del set (entradas y
+// condiciones), el tipo de incidencia, la versión de prompts y el modelo. Si el
+// hash no cambia, el resultado de la evaluación anterior sigue siendo válido
+// aunque Jira haya actualizado la incidencia (etiquetas, sprint, comentarios, etc.).
+//
+// Los comentarios se excluyen salvo que EVAL_HASH_COMMENTS esté habilitado: un
+// comentario nuevo no debería volver a puntuar la incidencia por sí solo.
+//
+// El tipo, los componentes y las etiquetas se incluyen siempre: el tipo y los
+// componentes deciden el set de prompts, y las plantillas suelen ramificar por
+// los tres. Componentes y etiquetas se ordenan para que Jira reordenándolos no
+// cuente como cambio. Otros metadatos usados en plantillas no provocan una
+// re-evaluación por sí solos.
+//
+// La versión de prompts se recibe como parámetro para poder recalcular el hash
+// con la versión guardada en caché y distinguir un cambio de contenido de un
+// cambio de prompt.
+func (c *Client) InputHash(incident *jira.Incident, promptVersion string) string {
+	set, _ := c.prompts.Load().Resolve(incident)
+
+	h := sha256.New()
+	parts := []string{c.model, promptVersion, incident.IssueType,
+		sortedJoin(incident.Components), sortedJoin(incident.Labels)}
+	for _, input := range set.Inputs() {
+		if input == commentsInput && !c.hashComments {
+			continue
+		}
+		parts = append(parts, input, incidentInputs[input].value(incident))
+	}
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0}) // separador para que "ab"+"c" no colisione con "a"+"bc"
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// sortedJoin une una copia ordenada de los valores
+func sortedJoin(values []string) string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
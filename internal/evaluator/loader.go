@@ -1,27 +1,270 @@
 package evaluator
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/PhelGc/furina-sync/internal/config"
+	"github.com/PhelGc/furina-sync/internal/jira"
 )
 
-// PromptLoader almacena los prompts de sistema leídos desde archivos externos.
+// versionHeader permite fijar la versión de un prompt de forma explícita con una
+// primera línea del estilo "# version: 2026-03-rubrica-v2". La línea no se envía al modelo.
+const versionHeader = "# version:"
+
+// DefaultSet es el nombre del set de prompts que se usa cuando ninguna ruta coincide
+const DefaultSet = "default"
+
+// PromptLoader almacena los sets de prompts leídos desde archivos externos y la
+// tabla de ruteo que decide qué set evalúa cada incidencia.
 // Se cargan una sola vez al iniciar para evitar I/O repetido en cada evaluación.
 type PromptLoader struct {
-	Phase1 string
-	Phase2 string
+	Sets   map[string]*PromptSet
+	Routes []config.PromptRoute
+	files  []string // archivos leídos, para vigilar cambios
+}
+
+// PromptSet es una rúbrica: pipeline de fases con sus prompts y umbral de puntaje aceptable
+type PromptSet struct {
+	Name      string
+	Phases    []*Phase
+	Threshold int
+	defHash   string // hash del pipeline.json, vacío si se usa el pipeline por defecto
+}
+
+// Prompt es una plantilla text/template de prompt de sistema. Un archivo sin
+// acciones de plantilla se envía tal cual.
+type Prompt struct {
+	Text    string // contenido del archivo sin el encabezado de versión
+	Version string
+	tmpl    *template.Template
 }
 
-// LoadPrompts lee los archivos de prompts desde disco.
-// Falla explícitamente si algún archivo no existe.
-func LoadPrompts(phase1Path, phase2Path string) (*PromptLoader, error) {
-	p1, err := os.ReadFile(phase1Path)
+// PromptData son los datos disponibles dentro de las plantillas de prompts:
+// {{.Incident.IssueType}}, {{.Incident.Status}}, {{.Phases.descripcion.Score}},
+// {{.Phases.descripcion.Field "causa_raiz"}}, etc. Phases contiene las fases
+// anteriores que ya se ejecutaron.
+type PromptData struct {
+	Incident *jira.Incident
+	Phases   map[string]*PhaseResult
+}
+
+// LoadPrompts lee y compila el set por defecto y los sets referenciados por las
+// rutas. Cada set declara sus fases en un pipeline.json; sin ese archivo se usa
+// el pipeline de dos fases (descripción y conclusión) con phase1.txt y
+// phase2.txt opcional. Falla explícitamente si falta algún archivo obligatorio
+// o si el pipeline o alguna plantilla no son válidos.
+func LoadPrompts(cfg config.EvalConfig) (*PromptLoader, error) {
+	loader := &PromptLoader{Sets: make(map[string]*PromptSet), Routes: cfg.Routes}
+
+	defaultDef := defaultPipeline(cfg.PromptPhase1, cfg.PromptPhase2)
+	defaultFile := cfg.PipelineFile
+	if defaultFile != "" {
+		def, err := readPipeline(defaultFile)
+		if err != nil {
+			return nil, fmt.Errorf("no se pudo cargar pipeline (%s): %w", defaultFile, err)
+		}
+		defaultDef = def
+	}
+	set, err := loader.loadSet(DefaultSet, defaultDef, defaultFile, cfg.ScoreThreshold)
 	if err != nil {
-		return nil, fmt.Errorf("no se pudo cargar prompt fase 1 (%s): %w", phase1Path, err)
+		return nil, err
 	}
-	p2, err := os.ReadFile(phase2Path)
+	loader.Sets[DefaultSet] = set
+
+	for _, route := range cfg.Routes {
+		if _, loaded := loader.Sets[route.Set]; loaded {
+			continue
+		}
+
+		dir := filepath.Join(cfg.PromptSetsDir, route.Set)
+		pipelineFile := filepath.Join(dir, "pipeline.json")
+		var def PipelineConfig
+		if _, statErr := os.Stat(pipelineFile); statErr == nil {
+			if def, err = readPipeline(pipelineFile); err != nil {
+				return nil, fmt.Errorf("set %s: no se pudo cargar pipeline (%s): %w", route.Set, pipelineFile, err)
+			}
+		} else {
+			// Sin phase2.txt el set omite la evaluación de conclusión
+			pipelineFile = ""
+			phase2Path := filepath.Join(dir, "phase2.txt")
+			if _, statErr := os.Stat(phase2Path); statErr != nil {
+				phase2Path = ""
+			}
+			def = defaultPipeline(filepath.Join(dir, "phase1.txt"), phase2Path)
+		}
+
+		if loader.Sets[route.Set], err = loader.loadSet(route.Set, def, pipelineFile, cfg.ScoreThreshold); err != nil {
+			return nil, err
+		}
+	}
+
+	return loader, nil
+}
+
+// loadSet valida el pipeline y compila los prompts de sus fases. Cada plantilla
+// se ejecuta una vez con datos vacíos (incluidas las fases anteriores) para
+// detectar campos inexistentes al cargar y no en la primera evaluación.
+func (p *PromptLoader) loadSet(name string, def PipelineConfig, pipelineFile string, threshold int) (*PromptSet, error) {
+	if err := def.validate(); err != nil {
+		return nil, fmt.Errorf("set %s: %w", name, err)
+	}
+
+	set := &PromptSet{Name: name, Threshold: threshold}
+	if pipelineFile != "" {
+		p.files = append(p.files, pipelineFile)
+		defJSON, _ := json.Marshal(def)
+		sum := sha256.Sum256(defJSON)
+		set.defHash = hex.EncodeToString(sum[:])[:8]
+	}
+
+	sample := PromptData{Incident: &jira.Incident{}, Phases: make(map[string]*PhaseResult)}
+	for _, phaseDef := range def.Phases {
+		phase := &Phase{PhaseConfig: phaseDef}
+		if phaseDef.Prompt != "" {
+			prompt, err := p.loadPrompt(phaseDef.Prompt)
+			if err != nil {
+				return nil, fmt.Errorf("set %s: no se pudo cargar prompt de la fase %s (%s): %w", name, phaseDef.Name, phaseDef.Prompt, err)
+			}
+			if _, err := prompt.Render(sample); err != nil {
+				return nil, fmt.Errorf("set %s, fase %s: %w", name, phaseDef.Name, err)
+			}
+			phase.Prompt = prompt
+		}
+		sample.Phases[phaseDef.Name] = &PhaseResult{Name: phaseDef.Name, Label: phaseDef.Label}
+		set.Phases = append(set.Phases, phase)
+	}
+	return set, nil
+}
+
+// Resolve devuelve el set de prompts y el umbral que corresponden a la incidencia.
+// Las rutas se evalúan en orden y gana la primera que coincide; el proyecto se
+// toma del prefijo de la key (PROJ-123 → PROJ) y el componente debe estar entre
+// los componentes de la incidencia.
+func (p *PromptLoader) Resolve(incident *jira.Incident) (*PromptSet, int) {
+	project, _, _ := strings.Cut(incident.Key, "-")
+	for _, route := range p.Routes {
+		if !strings.EqualFold(route.IssueType, incident.IssueType) {
+			continue
+		}
+		if route.Project != "" && !strings.EqualFold(route.Project, project) {
+			continue
+		}
+		if route.Component != "" && !hasComponent(incident, route.Component) {
+			continue
+		}
+		set := p.Sets[route.Set]
+		threshold := set.Threshold
+		if route.Threshold > 0 {
+			threshold = route.Threshold
+		}
+		return set, threshold
+	}
+
+	set := p.Sets[DefaultSet]
+	return set, set.Threshold
+}
+
+// hasComponent indica si la incidencia pertenece al componente
+func hasComponent(incident *jira.Incident, component string) bool {
+	for _, c := range incident.Components {
+		if strings.EqualFold(c, component) {
+			return true
+		}
+	}
+	return false
+}
+
+// Version identifica el set y los prompts con que se evalúa la incidencia. Se
+// guarda junto a cada evaluación para saber con qué rúbrica se generó el puntaje.
+func (p *PromptLoader) Version(incident *jira.Incident) string {
+	set, _ := p.Resolve(incident)
+	return set.Version()
+}
+
+// Files devuelve las rutas de todos los archivos de prompts cargados
+func (p *PromptLoader) Files() []string {
+	return p.files
+}
+
+// Version identifica el set, la versión del prompt de cada fase y, si el set
+// usa pipeline.json, un hash de su definición
+func (s *PromptSet) Version() string {
+	versions := make([]string, len(s.Phases))
+	for i, phase := range s.Phases {
+		versions[i] = "-"
+		if phase.Prompt != nil {
+			versions[i] = phase.Prompt.Version
+		}
+	}
+	version := s.Name + ":" + strings.Join(versions, "/")
+	if s.defHash != "" {
+		version += "+" + s.defHash
+	}
+	return version
+}
+
+// Inputs devuelve los campos de la incidencia que usa alguna fase del set,
+// como entrada o como condición de ejecución
+func (s *PromptSet) Inputs() []string {
+	seen := make(map[string]bool)
+	var inputs []string
+	for _, phase := range s.Phases {
+		for _, input := range append(append([]string{}, phase.Inputs...), phase.RunIf.NonEmpty...) {
+			if !seen[input] {
+				seen[input] = true
+				inputs = append(inputs, input)
+			}
+		}
+	}
+	sort.Strings(inputs)
+	return inputs
+}
+
+// Render ejecuta la plantilla con los datos de la incidencia
+func (p *Prompt) Render(data PromptData) (string, error) {
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error renderizando prompt: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// loadPrompt lee un archivo de prompt, separa su versión y compila la plantilla
+func (p *PromptLoader) loadPrompt(path string) (*Prompt, error) {
+	raw, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("no se pudo cargar prompt fase 2 (%s): %w", phase2Path, err)
+		return nil, err
 	}
-	return &PromptLoader{Phase1: string(p1), Phase2: string(p2)}, nil
+	p.files = append(p.files, path)
+
+	text, version := parsePrompt(string(raw))
+	tmpl, err := template.New(path).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("plantilla inválida: %w", err)
+	}
+	return &Prompt{Text: text, Version: version, tmpl: tmpl}, nil
+}
+
+// parsePrompt separa el encabezado de versión del texto del prompt. Si no hay
+// encabezado, la versión es un hash corto del contenido.
+func parsePrompt(raw string) (text, version string) {
+	firstLine, rest, _ := strings.Cut(raw, "\n")
+	if strings.HasPrefix(strings.TrimSpace(firstLine), versionHeader) {
+		version = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(firstLine), versionHeader))
+		if version != "" {
+			return rest, version
+		}
+	}
+
+	sum := sha256.Sum256([]byte(raw))
+	return raw, hex.EncodeToString(sum[:])[:12]
 }
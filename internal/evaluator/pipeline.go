@@ -0,0 +1,301 @@
+package evaluator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PhelGc/furina-sync/internal/jira"
+)
+
+// Claves estándar que toda fase debe devolver además de su esquema de salida
+const (
+	scoreKey = "puntaje"
+	notesKey = "observaciones"
+)
+
+// Tipos admitidos en el esquema de salida de una fase
+const (
+	OutputString = "string"
+	OutputBool   = "bool"
+	OutputNumber = "number"
+)
+
+// PipelineConfig declara las fases de un set de prompts (archivo pipeline.json)
+type PipelineConfig struct {
+	Phases []PhaseConfig `json:"phases"`
+}
+
+// PhaseConfig declara una fase del pipeline de evaluación
+type PhaseConfig struct {
+	Name        string        `json:"name"`
+	Label       string        `json:"label"`                  // título de la fase en Discord
+	Prompt      string        `json:"prompt"`                 // ruta relativa al archivo pipeline.json
+	Inputs      []string      `json:"inputs"`                 // campos de la incidencia enviados al modelo
+	DependsOn   []string      `json:"depends_on,omitempty"`   // fases anteriores cuyo resultado se envía
+	RunIf       RunCondition  `json:"run_if,omitempty"`       // condición para ejecutar la fase
+	SkipMessage string        `json:"skip_message,omitempty"` // texto mostrado si la fase no se ejecuta
+	Optional    bool          `json:"optional,omitempty"`     // un error en la fase no invalida la evaluación
+	Output      []OutputField `json:"output"`                 // campos que debe devolver el modelo
+}
+
+// RunCondition condición de ejecución de una fase; todas las partes deben cumplirse
+type RunCondition struct {
+	NonEmpty   []string `json:"non_empty,omitempty"`   // campos de la incidencia que no pueden estar vacíos
+	IssueTypes []string `json:"issue_types,omitempty"` // tipos de incidencia admitidos (vacío = todos)
+}
+
+// OutputField campo del esquema de salida de una fase
+type OutputField struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	Type  string `json:"type"` // string / bool / number
+}
+
+// Phase es una fase cargada con su plantilla de prompt compilada
+type Phase struct {
+	PhaseConfig
+	Prompt *Prompt
+}
+
+// commentsInput es la entrada con el extracto de comentarios y worklog. Se excluye
+// del hash de caché salvo que EVAL_HASH_COMMENTS esté habilitado.
+const commentsInput = "comments"
+
+// incidentInput describe un campo de la incidencia que puede usarse como entrada
+type incidentInput struct {
+	label     string
+	multiline bool
+	omitEmpty bool // no se envía al modelo si está vacío
+	value     func(*jira.Incident) string
+}
+
+// incidentInputs campos de la incidencia disponibles en "inputs" y "run_if.non_empty"
+var incidentInputs = map[string]incidentInput{
+	"title":       {"Título", false, false, func(i *jira.Incident) string { return i.Title }},
+	"description": {"Descripción", true, false, func(i *jira.Incident) string { return i.Description }},
+	"conclusion":  {"Conclusión de la incidencia", true, false, func(i *jira.Incident) string { return i.Conclusion }},
+	"status":      {"Estado", false, false, func(i *jira.Incident) string { return i.Status }},
+	"issue_type":  {"Tipo", false, false, func(i *jira.Incident) string { return i.IssueType }},
+	"assignee":    {"Assignee", false, false, func(i *jira.Incident) string { return i.Assignee }},
+	"priority":    {"Prioridad", false, false, func(i *jira.Incident) string { return i.Priority }},
+	"labels":      {"Etiquetas", false, false, func(i *jira.Incident) string { return strings.Join(i.Labels, ", ") }},
+	"components":  {"Componentes", false, false, func(i *jira.Incident) string { return strings.Join(i.Components, ", ") }},
+	"reporter":    {"Reportada por", false, false, func(i *jira.Incident) string { return i.Reporter }},
+	"sprint":      {"Sprint", false, true, func(i *jira.Incident) string { return i.SprintName }},
+	"parent":      {"Incidencia padre", false, true, parentInput},
+	commentsInput: {"Comentarios recientes (más reciente primero)", true, true, func(i *jira.Incident) string { return jira.FormatComments(i.Comments) }},
+}
+
+// parentInput describe el padre o epic de la incidencia
+func parentInput(i *jira.Incident) string {
+	switch {
+	case i.ParentKey != "" && i.ParentSummary != "":
+		return i.ParentKey + " — " + i.ParentSummary
+	case i.ParentKey != "":
+		return i.ParentKey
+	default:
+		return i.EpicKey
+	}
+}
+
+// defaultPipeline reproduce las dos fases históricas: descripción y conclusión.
+// Se usa cuando el set no tiene pipeline.json; phase2Path vacío omite la conclusión.
+func defaultPipeline(phase1Path, phase2Path string) PipelineConfig {
+	pipeline := PipelineConfig{Phases: []PhaseConfig{{
+		Name:   "descripcion",
+		Label:  "Descripción",
+		Prompt: phase1Path,
+		Inputs: []string{"title", "description", commentsInput},
+		Output: []OutputField{
+			{Key: "claridad", Label: "Claridad", Type: OutputString},
+			{Key: "causa_raiz", Label: "Causa raíz", Type: OutputString},
+			{Key: "impacto_definido", Label: "Impacto", Type: OutputBool},
+		},
+	}}}
+
+	conclusion := PhaseConfig{
+		Name:        "conclusion",
+		Label:       "Conclusión",
+		Prompt:      phase2Path,
+		Inputs:      []string{"conclusion", commentsInput},
+		DependsOn:   []string{"descripcion"},
+		RunIf:       RunCondition{NonEmpty: []string{"conclusion"}},
+		SkipMessage: "Sin conclusión — evaluación pendiente",
+		Optional:    true,
+		Output: []OutputField{
+			{Key: "coherencia_con_descripcion", Label: "Coherencia", Type: OutputBool},
+			{Key: "acciones_definidas", Label: "Acciones", Type: OutputBool},
+			{Key: "responsables_asignados", Label: "Responsables", Type: OutputBool},
+		},
+	}
+	if phase2Path == "" {
+		conclusion.SkipMessage = "No se evalúa para este tipo de incidencia"
+	}
+	pipeline.Phases = append(pipeline.Phases, conclusion)
+	return pipeline
+}
+
+// readPipeline lee un pipeline.json; las rutas de prompts se resuelven
+// relativas al directorio del archivo
+func readPipeline(path string) (PipelineConfig, error) {
+	var pipeline PipelineConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return pipeline, err
+	}
+	if err := json.Unmarshal(data, &pipeline); err != nil {
+		return pipeline, fmt.Errorf("JSON inválido: %w", err)
+	}
+	for i := range pipeline.Phases {
+		if p := pipeline.Phases[i].Prompt; p != "" && !filepath.IsAbs(p) {
+			pipeline.Phases[i].Prompt = filepath.Join(filepath.Dir(path), p)
+		}
+	}
+	return pipeline, nil
+}
+
+// validate revisa que el pipeline sea coherente antes de usarlo
+func (p PipelineConfig) validate() error {
+	if len(p.Phases) == 0 {
+		return fmt.Errorf("el pipeline no declara fases")
+	}
+
+	seen := make(map[string]bool)
+	for _, phase := range p.Phases {
+		if phase.Name == "" {
+			return fmt.Errorf("hay una fase sin nombre")
+		}
+		if seen[phase.Name] {
+			return fmt.Errorf("fase %s declarada dos veces", phase.Name)
+		}
+		for _, dep := range phase.DependsOn {
+			if !seen[dep] {
+				return fmt.Errorf("fase %s depende de %s, que no es una fase anterior", phase.Name, dep)
+			}
+		}
+		for _, input := range append(append([]string{}, phase.Inputs...), phase.RunIf.NonEmpty...) {
+			if _, ok := incidentInputs[input]; !ok {
+				return fmt.Errorf("fase %s: campo de entrada desconocido %q", phase.Name, input)
+			}
+		}
+		for _, out := range phase.Output {
+			switch out.Type {
+			case OutputString, OutputBool, OutputNumber:
+			default:
+				return fmt.Errorf("fase %s: tipo de salida inválido %q en %s", phase.Name, out.Type, out.Key)
+			}
+		}
+		seen[phase.Name] = true
+	}
+	return nil
+}
+
+// shouldRun evalúa la condición de ejecución de la fase. Una fase sin prompt
+// (por ejemplo la conclusión de un set sin phase2.txt) nunca se ejecuta.
+func (p *Phase) shouldRun(incident *jira.Incident, results map[string]*PhaseResult) bool {
+	if p.Prompt == nil {
+		return false
+	}
+	if len(p.RunIf.IssueTypes) > 0 {
+		matched := false
+		for _, t := range p.RunIf.IssueTypes {
+			if strings.EqualFold(t, incident.IssueType) {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for _, field := range p.RunIf.NonEmpty {
+		if strings.TrimSpace(incidentInputs[field].value(incident)) == "" {
+			return false
+		}
+	}
+	// Sin el resultado de una dependencia la fase no tiene contexto suficiente
+	for _, dep := range p.DependsOn {
+		if !results[dep].Scored() {
+			return false
+		}
+	}
+	return true
+}
+
+// skipMessage texto que se muestra cuando la fase no se ejecuta
+func (p *Phase) skipMessage() string {
+	if p.SkipMessage != "" {
+		return p.SkipMessage
+	}
+	return "No aplica"
+}
+
+// userMessage arma el mensaje con los resultados de las dependencias y las
+// entradas declaradas de la incidencia
+func (p *Phase) userMessage(incident *jira.Incident, results map[string]*PhaseResult) string {
+	var parts []string
+	for _, dep := range p.DependsOn {
+		depJSON, _ := json.Marshal(results[dep].asMap())
+		parts = append(parts, fmt.Sprintf("Resultado evaluación %s:\n%s", results[dep].Label, depJSON))
+	}
+	for _, name := range p.Inputs {
+		input := incidentInputs[name]
+		if input.omitEmpty && strings.TrimSpace(input.value(incident)) == "" {
+			continue
+		}
+		if input.multiline {
+			parts = append(parts, fmt.Sprintf("%s:\n%s", input.label, input.value(incident)))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s: %s", input.label, input.value(incident)))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// parseOutput valida la respuesta del modelo contra el esquema de la fase
+func (p *Phase) parseOutput(text string) (*PhaseResult, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(cleanJSON(text)), &raw); err != nil {
+		return nil, fmt.Errorf("JSON inválido (%q): %w", text, err)
+	}
+
+	score, ok := raw[scoreKey].(float64)
+	if !ok {
+		return nil, fmt.Errorf("falta %q numérico en la respuesta", scoreKey)
+	}
+	notes, _ := raw[notesKey].(string)
+
+	result := &PhaseResult{Name: p.Name, Label: p.Label, Score: int(score), Notes: notes}
+	for _, field := range p.Output {
+		value, exists := raw[field.Key]
+		if !exists {
+			return nil, fmt.Errorf("falta el campo %q en la respuesta", field.Key)
+		}
+		valid := false
+		switch field.Type {
+		case OutputString:
+			_, valid = value.(string)
+		case OutputBool:
+			_, valid = value.(bool)
+		case OutputNumber:
+			_, valid = value.(float64)
+		}
+		if !valid {
+			return nil, fmt.Errorf("el campo %q debe ser de tipo %s", field.Key, field.Type)
+		}
+		result.Outputs = append(result.Outputs, OutputValue{Key: field.Key, Label: field.Label, Type: field.Type, Value: value})
+	}
+	return result, nil
+}
+
+// asMap reconstruye la respuesta original de la fase, para enviarla como
+// contexto a las fases que dependen de ella
+func (p *PhaseResult) asMap() map[string]interface{} {
+	m := map[string]interface{}{scoreKey: p.Score, notesKey: p.Notes}
+	for _, out := range p.Outputs {
+		m[out.Key] = out.Value
+	}
+	return m
+}
@@ -1,26 +1,87 @@
 package evaluator
 
-// EvaluationResult contiene los resultados de ambas fases de evaluación
+import "encoding/json"
+
+// EvaluationResult contiene los resultados de las fases del pipeline de evaluación
 type EvaluationResult struct {
-	IncidentKey string
-	Phase1      *Phase1Result
-	Phase2      *Phase2Result // nil si la incidencia no tiene conclusión
-}
-
-// Phase1Result resultado de evaluación de título + descripción
-type Phase1Result struct {
-	Claridad        string `json:"claridad"`         // Alta / Media / Baja
-	CausaRaiz       string `json:"causa_raiz"`        // Identificada / Parcial / Ausente
-	ImpactoDefinido bool   `json:"impacto_definido"`
-	Puntaje         int    `json:"puntaje"`           // 0–100
-	Observaciones   string `json:"observaciones"`
-}
-
-// Phase2Result resultado de evaluación de conclusión
-type Phase2Result struct {
-	CoherenciaConDesc bool   `json:"coherencia_con_descripcion"`
-	AccionesDefinidas bool   `json:"acciones_definidas"`
-	ResponsablesAsig  bool   `json:"responsables_asignados"`
-	Puntaje           int    `json:"puntaje"` // 0–100
-	Observaciones     string `json:"observaciones"`
+	IncidentKey   string         `json:"incident_key"`
+	PromptVersion string         `json:"prompt_version"` // versión del set usado (ver PromptSet.Version)
+	PromptSet     string         `json:"prompt_set"`     // set de prompts que evaluó la incidencia
+	Threshold     int            `json:"threshold"`      // puntaje mínimo aceptable para el set
+	Phases        []*PhaseResult `json:"phases"`         // una entrada por fase del pipeline, en orden
+}
+
+// PhaseResult resultado de una fase. Si la fase no se ejecutó, Skipped contiene
+// el motivo y Score/Outputs quedan vacíos.
+type PhaseResult struct {
+	Name    string        `json:"name"`
+	Label   string        `json:"label"`
+	Score   int           `json:"score"` // 0–100
+	Notes   string        `json:"notes"`
+	Outputs []OutputValue `json:"outputs"`
+	Skipped string        `json:"skipped,omitempty"`
+}
+
+// OutputValue valor de un campo del esquema de salida de una fase
+type OutputValue struct {
+	Key   string      `json:"key"`
+	Label string      `json:"label"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// Scored indica si la fase se ejecutó y tiene puntaje
+func (p *PhaseResult) Scored() bool {
+	return p != nil && p.Skipped == ""
+}
+
+// Field devuelve el valor de un campo de salida, o nil si no existe.
+// Útil en plantillas: {{.Phases.descripcion.Field "causa_raiz"}}
+func (p *PhaseResult) Field(key string) interface{} {
+	for _, out := range p.Outputs {
+		if out.Key == key {
+			return out.Value
+		}
+	}
+	return nil
+}
+
+// Phase devuelve el resultado de la fase con ese nombre, o nil si no existe
+func (r *EvaluationResult) Phase(name string) *PhaseResult {
+	for _, phase := range r.Phases {
+		if phase.Name == name {
+			return phase
+		}
+	}
+	return nil
+}
+
+// Scored devuelve las fases que se ejecutaron, en orden
+func (r *EvaluationResult) Scored() []*PhaseResult {
+	var scored []*PhaseResult
+	for _, phase := range r.Phases {
+		if phase.Scored() {
+			scored = append(scored, phase)
+		}
+	}
+	return scored
+}
+
+// AverageScore promedia el puntaje de las fases ejecutadas
+func (r *EvaluationResult) AverageScore() int {
+	scored := r.Scored()
+	if len(scored) == 0 {
+		return 0
+	}
+	total := 0
+	for _, phase := range scored {
+		total += phase.Score
+	}
+	return total / len(scored)
+}
+
+// PhasesJSON serializa las fases para guardarlas en base de datos
+func (r *EvaluationResult) PhasesJSON() (string, error) {
+	data, err := json.Marshal(r.Phases)
+	return string(data), err
 }
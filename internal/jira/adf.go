@@ -0,0 +1,328 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// adfNode nodo de un documento ADF (Atlassian Document Format)
+type adfNode struct {
+	Type    string                 `json:"type"`
+	Text    string                 `json:"text"`
+	Attrs   map[string]interface{} `json:"attrs"`
+	Marks   []adfMark              `json:"marks"`
+	Content []adfNode              `json:"content"`
+}
+
+// adfMark formato aplicado a un nodo de texto (negrita, enlace, código, etc.)
+type adfMark struct {
+	Type  string                 `json:"type"`
+	Attrs map[string]interface{} `json:"attrs"`
+}
+
+// panelLabels título con que se muestra cada tipo de panel
+var panelLabels = map[string]string{
+	"info":    "Info",
+	"note":    "Nota",
+	"warning": "Advertencia",
+	"error":   "Error",
+	"success": "Éxito",
+	"tip":     "Consejo",
+}
+
+// ADFToMarkdown convierte un campo de Jira a Markdown. Acepta un documento ADF,
+// un string JSON (campos de texto plano) o null. Conserva párrafos, títulos,
+// listas, bloques de código, citas, paneles, tablas, menciones y enlaces para
+// que el evaluador vea la estructura original del texto.
+func ADFToMarkdown(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+
+	var plain string
+	if err := json.Unmarshal(raw, &plain); err == nil {
+		return strings.TrimSpace(plain)
+	}
+
+	var doc adfNode
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(renderBlock(doc))
+}
+
+// renderBlocks renderiza nodos de bloque separados por una línea en blanco
+func renderBlocks(nodes []adfNode) string {
+	var blocks []string
+	for _, node := range nodes {
+		if block := renderBlock(node); strings.TrimSpace(block) != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// renderBlock renderiza un nodo de bloque
+func renderBlock(node adfNode) string {
+	switch node.Type {
+	case "doc":
+		return renderBlocks(node.Content)
+	case "paragraph":
+		return renderInline(node.Content)
+	case "heading":
+		level := attrInt(node.Attrs, "level", 1)
+		if level < 1 || level > 6 {
+			level = 1
+		}
+		return strings.Repeat("#", level) + " " + renderInline(node.Content)
+	case "bulletList", "orderedList", "taskList", "decisionList":
+		return renderList(node)
+	case "codeBlock":
+		return "```" + attrString(node.Attrs, "language") + "\n" + plainText(node.Content) + "\n```"
+	case "blockquote":
+		return quote(renderBlocks(node.Content))
+	case "panel":
+		label := panelLabels[attrString(node.Attrs, "panelType")]
+		if label == "" {
+			label = "Nota"
+		}
+		return quote("**" + label + ":** " + renderBlocks(node.Content))
+	case "rule":
+		return "---"
+	case "table":
+		return renderTable(node)
+	case "expand", "nestedExpand":
+		body := renderBlocks(node.Content)
+		if title := attrString(node.Attrs, "title"); title != "" {
+			return "**" + title + "**\n\n" + body
+		}
+		return body
+	case "mediaSingle", "mediaGroup":
+		return renderBlocks(node.Content)
+	case "media":
+		if alt := attrString(node.Attrs, "alt"); alt != "" {
+			return "[adjunto: " + alt + "]"
+		}
+		return "[adjunto]"
+	case "blockCard", "embedCard":
+		return attrString(node.Attrs, "url")
+	case "text", "hardBreak", "mention", "emoji", "inlineCard", "status", "date":
+		return renderInline([]adfNode{node})
+	default:
+		// Nodos desconocidos: conservar al menos su contenido
+		if len(node.Content) > 0 {
+			return renderBlocks(node.Content)
+		}
+		return renderInline([]adfNode{node})
+	}
+}
+
+// renderInline renderiza nodos en línea (texto con formato, menciones, etc.)
+func renderInline(nodes []adfNode) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		switch node.Type {
+		case "text":
+			b.WriteString(applyMarks(node.Text, node.Marks))
+		case "hardBreak":
+			b.WriteString("\n")
+		case "mention":
+			name := strings.TrimPrefix(attrString(node.Attrs, "text"), "@")
+			b.WriteString("@" + name)
+		case "emoji":
+			if text := attrString(node.Attrs, "text"); text != "" {
+				b.WriteString(text)
+			} else {
+				b.WriteString(attrString(node.Attrs, "shortName"))
+			}
+		case "inlineCard":
+			b.WriteString(attrString(node.Attrs, "url"))
+		case "status":
+			b.WriteString("[" + attrString(node.Attrs, "text") + "]")
+		case "date":
+			b.WriteString(formatADFDate(attrString(node.Attrs, "timestamp")))
+		default:
+			b.WriteString(renderInline(node.Content))
+		}
+	}
+	return b.String()
+}
+
+// applyMarks envuelve el texto con la sintaxis Markdown de cada marca. Los
+// espacios de los extremos quedan fuera de los delimitadores para que el
+// Markdown resultante sea válido ("** hola**" no es negrita).
+func applyMarks(text string, marks []adfMark) string {
+	if len(marks) == 0 || strings.TrimSpace(text) == "" {
+		return text
+	}
+
+	core := strings.TrimSpace(text)
+	leading := text[:strings.Index(text, core)]
+	trailing := text[len(leading)+len(core):]
+
+	// El código va por dentro y el enlace por fuera, independientemente del orden en ADF
+	var link string
+	for _, mark := range marks {
+		if mark.Type == "code" {
+			core = "`" + core + "`"
+		}
+	}
+	for _, mark := range marks {
+		switch mark.Type {
+		case "em":
+			core = "*" + core + "*"
+		case "strong":
+			core = "**" + core + "**"
+		case "strike":
+			core = "~~" + core + "~~"
+		case "link":
+			link = attrString(mark.Attrs, "href")
+		}
+	}
+	if link != "" {
+		core = "[" + core + "](" + link + ")"
+	}
+	return leading + core + trailing
+}
+
+// renderList renderiza listas con viñetas, numeradas, de tareas y de decisiones.
+// El contenido de cada elemento que ocupa varias líneas (párrafos adicionales,
+// listas anidadas) se indenta bajo el marcador.
+func renderList(node adfNode) string {
+	order := attrInt(node.Attrs, "order", 1)
+
+	var items []string
+	for i, item := range node.Content {
+		var marker string
+		switch node.Type {
+		case "orderedList":
+			marker = strconv.Itoa(order+i) + ". "
+		case "taskList":
+			if attrString(item.Attrs, "state") == "DONE" {
+				marker = "- [x] "
+			} else {
+				marker = "- [ ] "
+			}
+		default:
+			marker = "- "
+		}
+
+		var parts []string
+		if item.Type == "taskItem" || item.Type == "decisionItem" {
+			// Las tareas y decisiones tienen contenido en línea directamente
+			parts = append(parts, renderInline(item.Content))
+		} else {
+			for _, child := range item.Content {
+				parts = append(parts, renderBlock(child))
+			}
+		}
+
+		indent := strings.Repeat(" ", len(marker))
+		lines := strings.Split(strings.Join(parts, "\n"), "\n")
+		for j := 1; j < len(lines); j++ {
+			if lines[j] != "" {
+				lines[j] = indent + lines[j]
+			}
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	}
+	return strings.Join(items, "\n")
+}
+
+// renderTable renderiza una tabla Markdown. La primera fila se usa como
+// encabezado porque Markdown lo exige aunque la tabla ADF no lo tenga.
+func renderTable(node adfNode) string {
+	var rows [][]string
+	columns := 0
+	for _, row := range node.Content {
+		var cells []string
+		for _, cell := range row.Content {
+			text := renderBlocks(cell.Content)
+			text = strings.ReplaceAll(text, "|", "\\|")
+			text = strings.Join(strings.Fields(strings.ReplaceAll(text, "\n", " ")), " ")
+			cells = append(cells, text)
+		}
+		if len(cells) > columns {
+			columns = len(cells)
+		}
+		rows = append(rows, cells)
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+
+	var lines []string
+	for i, cells := range rows {
+		for len(cells) < columns {
+			cells = append(cells, "")
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// plainText concatena el texto de los nodos sin aplicar marcas (bloques de código)
+func plainText(nodes []adfNode) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		switch node.Type {
+		case "text":
+			b.WriteString(node.Text)
+		case "hardBreak":
+			b.WriteString("\n")
+		default:
+			b.WriteString(plainText(node.Content))
+		}
+	}
+	return b.String()
+}
+
+// quote antepone "> " a cada línea
+func quote(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = ">"
+		} else {
+			lines[i] = "> " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatADFDate convierte el timestamp en milisegundos de un nodo date a YYYY-MM-DD
+func formatADFDate(timestamp string) string {
+	ms, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return timestamp
+	}
+	return time.UnixMilli(ms).UTC().Format("2006-01-02")
+}
+
+// attrString devuelve un atributo como string, o vacío si no existe
+func attrString(attrs map[string]interface{}, key string) string {
+	switch v := attrs[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// attrInt devuelve un atributo numérico, o def si no existe
+func attrInt(attrs map[string]interface{}, key string, def int) int {
+	if v, ok := attrs[key].(float64); ok {
+		return int(v)
+	}
+	return def
+}
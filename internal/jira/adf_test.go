@@ -0,0 +1,49 @@
+package jira
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// update regenera los archivos .md esperados: go test ./internal/jira -run TestADFToMarkdown -update
+var update = flag.Bool("update", false, "regenerar los archivos golden de testdata")
+
+// TestADFToMarkdown convierte cada testdata/*.json y compara con su .md
+func TestADFToMarkdown(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no hay casos en testdata")
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".json")
+		t.Run(name, func(t *testing.T) {
+			raw, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ADFToMarkdown(raw) + "\n"
+
+			golden := strings.TrimSuffix(input, ".json") + ".md"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("falta %s (generarlo con -update): %v", golden, err)
+			}
+			if got != strings.ReplaceAll(string(want), "\r\n", "\n") {
+				t.Errorf("salida distinta de %s\n--- obtenido ---\n%s--- esperado ---\n%s", golden, got, want)
+			}
+		})
+	}
+}
@@ -21,6 +21,10 @@ type Client struct {
 	status        string
 	assignee      string
 	currentSprint bool
+	fetchComments bool
+	fetchWorklog  bool
+	commentsMax   int
+	commentsChars int
 	httpClient    *http.Client
 }
 
@@ -31,11 +35,28 @@ type Incident struct {
 	Description string    `json:"description"`
 	Conclusion  string    `json:"conclusion"`
 	Status      string    `json:"status"`
-	IssueType   string    `json:"issue_type"` // Tipo de incidencia
-	Assignee    string    `json:"assignee"`   // Assignee de la incidencia
+	IssueType   string    `json:"issue_type"`  // Tipo de incidencia
+	Assignee    string    `json:"assignee"`    // Nombre visible del assignee
+	AssigneeID  string    `json:"assignee_id"` // accountId del assignee (identificador estable)
 	CreatedDate time.Time `json:"created_date"`
 	UpdatedDate time.Time `json:"updated_date"`
 	SyncDate    time.Time `json:"sync_date"`
+	Comments    []Comment `json:"comments,omitempty"` // extracto cargado con LoadComments
+
+	Priority       string    `json:"priority"`
+	Labels         []string  `json:"labels"`
+	Components     []string  `json:"components"`
+	Reporter       string    `json:"reporter"`
+	SprintID       int       `json:"sprint_id,omitempty"`
+	SprintName     string    `json:"sprint_name,omitempty"`
+	ResolutionDate time.Time `json:"resolution_date"` // cero si no está resuelta
+	ParentKey      string    `json:"parent_key,omitempty"`
+	ParentSummary  string    `json:"parent_summary,omitempty"`
+	EpicKey        string    `json:"epic_key,omitempty"` // Epic Link clásico; en proyectos nuevos el epic es el parent
+
+	// ADF original de los campos de texto; Description y Conclusion son su conversión a Markdown
+	DescriptionADF json.RawMessage `json:"description_adf,omitempty"`
+	ConclusionADF  json.RawMessage `json:"conclusion_adf,omitempty"`
 }
 
 // JiraSearchResponse estructura de respuesta de la API v3 de Jira
@@ -53,13 +74,21 @@ type JiraIssue struct {
 // JiraFields campos del issue
 type JiraFields struct {
 	Summary          string          `json:"summary"`
-	Description      interface{}     `json:"description"`
+	Description      json.RawMessage `json:"description"`
 	Status           JiraStatus      `json:"status"`
 	IssueType        JiraIssueType   `json:"issuetype"`
 	Assignee         *JiraUser       `json:"assignee"`
 	Resolution       *JiraResolution `json:"resolution"`
 	Created          string          `json:"created"`
 	Updated          string          `json:"updated"`
+	ResolutionDate   string          `json:"resolutiondate"`
+	Priority         *JiraPriority   `json:"priority"`
+	Labels           []string        `json:"labels"`
+	Components       []JiraComponent `json:"components"`
+	Reporter         *JiraUser       `json:"reporter"`
+	Parent           *JiraParent     `json:"parent"`
+	CustomField10020 json.RawMessage `json:"customfield_10020"` // Sprint
+	CustomField10014 json.RawMessage `json:"customfield_10014"` // Epic Link
 	CustomField10208 json.RawMessage `json:"customfield_10208"`
 	CustomField10207 json.RawMessage `json:"customfield_10207"`
 	CustomField10206 json.RawMessage `json:"customfield_10206"`
@@ -72,6 +101,7 @@ type JiraIssueType struct {
 
 // JiraUser representa un usuario de Jira
 type JiraUser struct {
+	AccountID   string `json:"accountId"`
 	DisplayName string `json:"displayName"`
 }
 
@@ -80,6 +110,31 @@ type JiraStatus struct {
 	Name string `json:"name"`
 }
 
+// JiraPriority prioridad del issue
+type JiraPriority struct {
+	Name string `json:"name"`
+}
+
+// JiraComponent componente del proyecto
+type JiraComponent struct {
+	Name string `json:"name"`
+}
+
+// JiraParent issue padre (epic o tarea padre de una subtarea)
+type JiraParent struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary string `json:"summary"`
+	} `json:"fields"`
+}
+
+// JiraSprint sprint del campo customfield_10020
+type JiraSprint struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	State string `json:"state"` // active / closed / future
+}
+
 // JiraResolution resolución del issue
 type JiraResolution struct {
 	Description string `json:"description"`
@@ -95,70 +150,24 @@ func NewClient(cfg config.JiraConfig) (*Client, error) {
 		status:        cfg.Status,
 		assignee:      cfg.Assignee,
 		currentSprint: cfg.CurrentSprint,
+		fetchComments: cfg.FetchComments,
+		fetchWorklog:  cfg.FetchWorklog,
+		commentsMax:   cfg.CommentsMax,
+		commentsChars: cfg.CommentsChars,
 		httpClient:    &http.Client{Timeout: 30 * time.Second},
 	}, nil
 }
 
-// extractTextFromADF extrae texto de campos con formato ADF (Atlassian Document Format)
-func extractTextFromADF(content interface{}) string {
-	if content == nil {
-		return ""
-	}
-
-	// Si es string directo, devolverlo
-	if str, ok := content.(string); ok {
-		return str
-	}
-
-	// Si es un mapa (objeto ADF)
-	if contentMap, ok := content.(map[string]interface{}); ok {
-		return extractTextFromADFMap(contentMap)
-	}
-
-	return ""
-}
-
-// extractTextFromADFMap extrae recursivamente texto de un mapa ADF
-func extractTextFromADFMap(contentMap map[string]interface{}) string {
-	var result strings.Builder
-
-	// Buscar en el contenido
-	if content, exists := contentMap["content"]; exists {
-		if contentArray, ok := content.([]interface{}); ok {
-			for _, item := range contentArray {
-				if itemMap, ok := item.(map[string]interface{}); ok {
-					result.WriteString(extractTextFromADFMap(itemMap))
-				}
-			}
-		}
-	}
-
-	// Buscar texto directo
-	if text, exists := contentMap["text"]; exists {
-		if textStr, ok := text.(string); ok {
-			result.WriteString(textStr)
-			result.WriteString(" ")
-		}
-	}
-
-	return strings.TrimSpace(result.String())
-}
-
 // extractConclusionFromFields busca la conclusión en los campos custom ya parseados.
-// Evita un segundo json.Unmarshal del body completo.
-func extractConclusionFromFields(fields JiraFields) string {
+// Evita un segundo json.Unmarshal del body completo. Devuelve el texto en Markdown
+// y el ADF original del campo.
+func extractConclusionFromFields(fields JiraFields) (string, json.RawMessage) {
 	for _, raw := range []json.RawMessage{fields.CustomField10208, fields.CustomField10207, fields.CustomField10206} {
-		if len(raw) == 0 || string(raw) == "null" {
-			continue
-		}
-		var content interface{}
-		if err := json.Unmarshal(raw, &content); err == nil {
-			if text := extractTextFromADF(content); text != "" {
-				return text
-			}
+		if text := ADFToMarkdown(raw); text != "" {
+			return text, raw
 		}
 	}
-	return ""
+	return "", nil
 }
 
 // GetIncidents obtiene las incidencias según los filtros configurados usando API v3
@@ -242,18 +251,19 @@ func (c *Client) GetIncidents() ([]*Incident, error) {
 	now := time.Now()
 
 	for _, issue := range searchResponse.Issues {
-		description := extractTextFromADF(issue.Fields.Description)
+		description := ADFToMarkdown(issue.Fields.Description)
 
 		// Conclusión desde custom fields (ya parseados en la struct)
-		conclusion := extractConclusionFromFields(issue.Fields)
+		conclusion, conclusionADF := extractConclusionFromFields(issue.Fields)
 		if conclusion == "" && issue.Fields.Resolution != nil {
 			conclusion = issue.Fields.Resolution.Description
 		}
 
 		// Extraer assignee
-		assignee := ""
+		assignee, assigneeID := "", ""
 		if issue.Fields.Assignee != nil {
 			assignee = issue.Fields.Assignee.DisplayName
+			assigneeID = issue.Fields.Assignee.AccountID
 		}
 
 		// Extraer tipo de issue
@@ -267,16 +277,40 @@ func (c *Client) GetIncidents() ([]*Incident, error) {
 		updatedDate := parseJiraDate(issue.Fields.Updated)
 
 		incident := &Incident{
-			Key:         issue.Key,
-			Title:       issue.Fields.Summary,
-			Description: description,
-			Conclusion:  conclusion,
-			Status:      issue.Fields.Status.Name,
-			IssueType:   issueType,
-			Assignee:    assignee,
-			CreatedDate: createdDate,
-			UpdatedDate: updatedDate,
-			SyncDate:    now,
+			Key:            issue.Key,
+			Title:          issue.Fields.Summary,
+			Description:    description,
+			Conclusion:     conclusion,
+			DescriptionADF: adfOrNil(issue.Fields.Description),
+			ConclusionADF:  conclusionADF,
+			Status:         issue.Fields.Status.Name,
+			IssueType:      issueType,
+			Assignee:       assignee,
+			AssigneeID:     assigneeID,
+			CreatedDate:    createdDate,
+			UpdatedDate:    updatedDate,
+			SyncDate:       now,
+			Labels:         issue.Fields.Labels,
+			Reporter:       displayName(issue.Fields.Reporter),
+			ResolutionDate: parseJiraDate(issue.Fields.ResolutionDate),
+		}
+
+		if issue.Fields.Priority != nil {
+			incident.Priority = issue.Fields.Priority.Name
+		}
+		for _, component := range issue.Fields.Components {
+			incident.Components = append(incident.Components, component.Name)
+		}
+		if sprint := currentSprintFromField(issue.Fields.CustomField10020); sprint != nil {
+			incident.SprintID = sprint.ID
+			incident.SprintName = sprint.Name
+		}
+		if issue.Fields.Parent != nil {
+			incident.ParentKey = issue.Fields.Parent.Key
+			incident.ParentSummary = issue.Fields.Parent.Fields.Summary
+		}
+		if len(issue.Fields.CustomField10014) > 0 {
+			json.Unmarshal(issue.Fields.CustomField10014, &incident.EpicKey)
 		}
 
 		incidents = append(incidents, incident)
@@ -285,6 +319,29 @@ func (c *Client) GetIncidents() ([]*Incident, error) {
 	return incidents, nil
 }
 
+// currentSprintFromField elige el sprint de la incidencia: el activo si lo hay,
+// si no el último de la lista (Jira los ordena del más antiguo al más reciente)
+func currentSprintFromField(raw json.RawMessage) *JiraSprint {
+	var sprints []JiraSprint
+	if len(raw) == 0 || json.Unmarshal(raw, &sprints) != nil || len(sprints) == 0 {
+		return nil
+	}
+	for i := range sprints {
+		if sprints[i].State == "active" {
+			return &sprints[i]
+		}
+	}
+	return &sprints[len(sprints)-1]
+}
+
+// adfOrNil descarta campos vacíos para no guardar "null" en el JSON de storage
+func adfOrNil(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	return raw
+}
+
 // parseJiraDate parsea fechas de Jira con manejo de diferentes formatos
 func parseJiraDate(dateStr string) time.Time {
 	if dateStr == "" {
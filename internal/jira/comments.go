@@ -0,0 +1,241 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tipos de comentario que se incluyen como contexto
+const (
+	CommentKindComment = "comentario"
+	CommentKindWorklog = "worklog"
+)
+
+// commentsPageSize tamaño de página para los endpoints paginados de comentarios y worklog
+const commentsPageSize = 100
+
+// maxCommentPages evita recorrer sin límite incidencias con miles de comentarios;
+// lo que queda fuera son siempre las entradas más antiguas
+const maxCommentPages = 10
+
+// Comment es un comentario o una entrada de worklog ya convertida a texto
+type Comment struct {
+	Kind    string    `json:"kind"`
+	Author  string    `json:"author"`
+	Created time.Time `json:"created"`
+	Body    string    `json:"body"`
+}
+
+// jiraCommentPage respuesta paginada de /issue/{key}/comment
+type jiraCommentPage struct {
+	StartAt    int `json:"startAt"`
+	MaxResults int `json:"maxResults"`
+	Total      int `json:"total"`
+	Comments   []struct {
+		Author  *JiraUser       `json:"author"`
+		Body    json.RawMessage `json:"body"`
+		Created string          `json:"created"`
+	} `json:"comments"`
+}
+
+// jiraWorklogPage respuesta paginada de /issue/{key}/worklog
+type jiraWorklogPage struct {
+	StartAt    int `json:"startAt"`
+	MaxResults int `json:"maxResults"`
+	Total      int `json:"total"`
+	Worklogs   []struct {
+		Author    *JiraUser       `json:"author"`
+		Comment   json.RawMessage `json:"comment"`
+		Started   string          `json:"started"`
+		TimeSpent string          `json:"timeSpent"`
+	} `json:"worklogs"`
+}
+
+// LoadComments descarga los comentarios (y el worklog si está habilitado) de la
+// incidencia y guarda en incident.Comments un extracto acotado, del más reciente
+// al más antiguo. No hace nada si la descarga de comentarios está deshabilitada.
+func (c *Client) LoadComments(incident *Incident) error {
+	if !c.fetchComments {
+		return nil
+	}
+
+	comments, err := c.getComments(incident.Key)
+	if err != nil {
+		return err
+	}
+	if c.fetchWorklog {
+		worklogs, err := c.getWorklogs(incident.Key)
+		if err != nil {
+			return err
+		}
+		comments = append(comments, worklogs...)
+	}
+
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].Created.After(comments[j].Created)
+	})
+	incident.Comments = boundComments(comments, c.commentsMax, c.commentsChars)
+	return nil
+}
+
+// getComments recorre las páginas de comentarios de una incidencia, de la más
+// reciente a la más antigua (orderBy=-created)
+func (c *Client) getComments(key string) ([]Comment, error) {
+	var comments []Comment
+	order := url.Values{"orderBy": {"-created"}}
+	for page, startAt := 0, 0; page < maxCommentPages; page++ {
+		var resp jiraCommentPage
+		if err := c.getPage("/rest/api/3/issue/"+url.PathEscape(key)+"/comment", startAt, order, &resp); err != nil {
+			return nil, fmt.Errorf("error obteniendo comentarios de %s: %v", key, err)
+		}
+
+		for _, raw := range resp.Comments {
+			body := ADFToMarkdown(raw.Body)
+			if strings.TrimSpace(body) == "" {
+				continue
+			}
+			comments = append(comments, Comment{
+				Kind:    CommentKindComment,
+				Author:  displayName(raw.Author),
+				Created: parseJiraDate(raw.Created),
+				Body:    body,
+			})
+		}
+
+		startAt = resp.StartAt + len(resp.Comments)
+		if len(resp.Comments) == 0 || startAt >= resp.Total {
+			break
+		}
+	}
+	return comments, nil
+}
+
+// getWorklogs recorre las páginas de worklog y conserva las entradas con
+// comentario. El endpoint no admite orderBy y devuelve del más antiguo al más
+// reciente, así que si no caben todas las páginas se empieza por el final.
+func (c *Client) getWorklogs(key string) ([]Comment, error) {
+	var worklogs []Comment
+	path := "/rest/api/3/issue/" + url.PathEscape(key) + "/worklog"
+	for page, startAt := 0, 0; page < maxCommentPages; page++ {
+		var resp jiraWorklogPage
+		if err := c.getPage(path, startAt, nil, &resp); err != nil {
+			return nil, fmt.Errorf("error obteniendo worklog de %s: %v", key, err)
+		}
+		if limit := maxCommentPages * commentsPageSize; page == 0 && resp.Total > limit {
+			// La primera página solo sirve para conocer el total
+			startAt = resp.Total - limit
+			if err := c.getPage(path, startAt, nil, &resp); err != nil {
+				return nil, fmt.Errorf("error obteniendo worklog de %s: %v", key, err)
+			}
+		}
+
+		for _, raw := range resp.Worklogs {
+			body := ADFToMarkdown(raw.Comment)
+			if strings.TrimSpace(body) == "" {
+				continue
+			}
+			worklogs = append(worklogs, Comment{
+				Kind:    CommentKindWorklog,
+				Author:  displayName(raw.Author),
+				Created: parseJiraDate(raw.Started),
+				Body:    fmt.Sprintf("(%s) %s", raw.TimeSpent, body),
+			})
+		}
+
+		startAt = resp.StartAt + len(resp.Worklogs)
+		if len(resp.Worklogs) == 0 || startAt >= resp.Total {
+			break
+		}
+	}
+	return worklogs, nil
+}
+
+// getPage hace un GET paginado a la API v3 y decodifica la respuesta en out;
+// extra agrega parámetros propios del endpoint (puede ser nil)
+func (c *Client) getPage(path string, startAt int, extra url.Values, out interface{}) error {
+	params := url.Values{}
+	for name, values := range extra {
+		params[name] = values
+	}
+	params.Add("startAt", strconv.Itoa(startAt))
+	params.Add("maxResults", strconv.Itoa(commentsPageSize))
+
+	req, err := http.NewRequest("GET", c.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("error creando request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(c.username, c.apiToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error haciendo request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error leyendo response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error en API de Jira (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("error parseando response: %v", err)
+	}
+	return nil
+}
+
+// boundComments conserva como máximo maxCount comentarios y maxChars caracteres
+// en total; el último comentario que entra se recorta si hace falta
+func boundComments(comments []Comment, maxCount, maxChars int) []Comment {
+	if maxCount > 0 && len(comments) > maxCount {
+		comments = comments[:maxCount]
+	}
+	if maxChars <= 0 {
+		return comments
+	}
+
+	var bounded []Comment
+	remaining := maxChars
+	for _, comment := range comments {
+		if remaining <= 0 {
+			break
+		}
+		if runes := []rune(comment.Body); len(runes) > remaining {
+			comment.Body = string(runes[:remaining]) + "…"
+		}
+		remaining -= len([]rune(comment.Body))
+		bounded = append(bounded, comment)
+	}
+	return bounded
+}
+
+// displayName devuelve el nombre visible de un usuario de Jira, o vacío si no hay usuario
+func displayName(user *JiraUser) string {
+	if user == nil {
+		return ""
+	}
+	return user.DisplayName
+}
+
+// FormatComments arma el extracto de comentarios que se envía al evaluador
+func FormatComments(comments []Comment) string {
+	var b strings.Builder
+	for i, comment := range comments {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "[%s · %s · %s]\n%s",
+			comment.Created.Format("2006-01-02 15:04"), comment.Author, comment.Kind, comment.Body)
+	}
+	return b.String()
+}
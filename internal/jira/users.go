@@ -0,0 +1,60 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// FindAccountID busca el accountId de un usuario a partir de su nombre visible.
+// Solo se acepta una coincidencia exacta y única: con nombres repetidos no hay
+// forma segura de saber a quién corresponde y se devuelve un error.
+func (c *Client) FindAccountID(displayName string) (string, error) {
+	params := url.Values{}
+	params.Add("query", displayName)
+	params.Add("maxResults", "50")
+
+	req, err := http.NewRequest("GET", c.baseURL+"/rest/api/3/user/search?"+params.Encode(), nil)
+	if err != nil {
+		return "", fmt.Errorf("error creando request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(c.username, c.apiToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error haciendo request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error leyendo response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error en API de Jira (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var users []JiraUser
+	if err := json.Unmarshal(body, &users); err != nil {
+		return "", fmt.Errorf("error parseando response: %v", err)
+	}
+
+	var matches []string
+	for _, user := range users {
+		if strings.EqualFold(strings.TrimSpace(user.DisplayName), strings.TrimSpace(displayName)) {
+			matches = append(matches, user.AccountID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no se encontró ningún usuario llamado %q", displayName)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("hay %d usuarios llamados %q", len(matches), displayName)
+	}
+}
@@ -0,0 +1,221 @@
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Store guarda el lease en un almacenamiento compartido por todas las
+// instancias (ver database.Repository)
+type Store interface {
+	AcquireLease(name, holder string, ttl time.Duration) (bool, error)
+	ReleaseLease(name, holder string) error
+	LeaseHolder(name string) (string, time.Time, error)
+}
+
+// Elector mantiene un lease con heartbeat. Solo la instancia que lo tiene es
+// líder; si deja de renovarlo (se cae, pierde la base de datos), otra lo toma
+// cuando vence.
+//
+// Cada período como líder tiene un contexto (ver Context) que se cancela en
+// cuanto se deja de serlo, para cortar la sincronización en curso en vez de
+// esperar a que termine.
+type Elector struct {
+	store Store
+	name  string
+	id    string
+	ttl   time.Duration
+
+	mu        sync.Mutex
+	leader    bool
+	renewedAt time.Time // último heartbeat exitoso como líder
+	parent    context.Context
+	ctx       context.Context    // contexto del período actual como líder
+	cancel    context.CancelFunc // nil si no es líder
+	expire    *time.Timer        // deja de ser líder si no se renueva a tiempo
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// New crea un elector para el lease name. id identifica a la instancia en los
+// logs y en la base de datos; si está vacío se genera uno con el hostname.
+func New(store Store, name, id string, ttl time.Duration) *Elector {
+	if id == "" {
+		id = defaultID()
+	}
+	return &Elector{
+		store: store,
+		name:  name,
+		id:    id,
+		ttl:   ttl,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// defaultID combina hostname, pid y un sufijo aleatorio: dos procesos en el
+// mismo host (p. ej. durante un deploy) no deben compartir identidad
+func defaultID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "furina"
+	}
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// ID devuelve la identidad de esta instancia
+func (e *Elector) ID() string {
+	return e.id
+}
+
+// Start intenta tomar el lease una vez (para saber si esta instancia arranca
+// como líder) y después lo renueva en segundo plano cada ttl/3. Los contextos
+// de Context derivan de parent: cancelarlo también corta la sincronización.
+func (e *Elector) Start(parent context.Context) {
+	e.parent = parent
+	e.expire = time.AfterFunc(e.ttl, e.checkExpired)
+	e.heartbeat()
+	if !e.IsLeader() {
+		e.logStandby()
+	}
+	go e.run()
+}
+
+func (e *Elector) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.heartbeat()
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// heartbeat toma o renueva el lease y registra los cambios de rol
+func (e *Elector) heartbeat() {
+	// El lease vence ttl después de la hora que usa AcquireLease, que es
+	// anterior a su respuesta: con una base de datos lenta, anclar el
+	// vencimiento a la respuesta dejaría a esta instancia como líder después de
+	// que otra pueda tomarlo
+	start := time.Now()
+	acquired, err := e.store.AcquireLease(e.name, e.id, e.ttl)
+
+	e.mu.Lock()
+	wasLeader := e.leader
+	if err != nil {
+		// Sin confirmación se sigue siendo líder solo hasta que vence el último
+		// lease renovado; IsLeader lo tiene en cuenta
+		log.Printf("Advertencia: error renovando lease de líder: %v", err)
+	} else {
+		e.leader = acquired
+		if acquired {
+			e.renewedAt = start
+			e.expire.Reset(e.ttl*2/3 - time.Since(start))
+		}
+	}
+	isLeader := e.leader
+	e.syncContext()
+	e.mu.Unlock()
+
+	switch {
+	case isLeader && !wasLeader:
+		log.Printf("[LÍDER] Esta instancia (%s) es líder: sincroniza", e.id)
+	case !isLeader && wasLeader:
+		log.Printf("[LÍDER] Esta instancia (%s) dejó de ser líder", e.id)
+		e.logStandby()
+	}
+}
+
+// checkExpired corre cuando pasan ttl*2/3 sin renovar el lease: cancela el
+// contexto del líder aunque el heartbeat siga esperando a la base de datos
+func (e *Elector) checkExpired() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cancel != nil && !e.leading() {
+		log.Printf("[LÍDER] Esta instancia (%s) no pudo renovar el lease a tiempo: se detiene la sincronización", e.id)
+	}
+	e.syncContext()
+}
+
+// syncContext crea el contexto del líder al tomar el lease y lo cancela al
+// perderlo. Se llama con mu tomado.
+func (e *Elector) syncContext() {
+	switch leading := e.leading(); {
+	case leading && e.cancel == nil:
+		e.ctx, e.cancel = context.WithCancel(e.parent)
+	case !leading && e.cancel != nil:
+		e.cancel()
+		e.cancel = nil
+	}
+}
+
+// logStandby informa quién tiene el lease
+func (e *Elector) logStandby() {
+	holder, expiresAt, err := e.store.LeaseHolder(e.name)
+	if err != nil {
+		return
+	}
+	log.Printf("[LÍDER] Instancia en espera (%s): el líder es %s, lease hasta %s",
+		e.id, holder, expiresAt.Local().Format("15:04:05"))
+}
+
+// IsLeader indica si esta instancia es líder. Si los heartbeats fallan deja de
+// serlo antes de que venza el lease, para no sincronizar a la vez que la
+// instancia que lo tome.
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leading()
+}
+
+func (e *Elector) leading() bool {
+	return e.leader && time.Since(e.renewedAt) < e.ttl*2/3
+}
+
+// Context devuelve el contexto del período actual como líder: se cancela al
+// dejar de serlo, al detener el elector o al cancelarse el contexto de Start.
+// Si esta instancia no es líder ya viene cancelado.
+func (e *Elector) Context() context.Context {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cancel == nil || !e.leading() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		return ctx
+	}
+	return e.ctx
+}
+
+// Stop detiene el heartbeat y libera el lease si esta instancia lo tenía, para
+// que otra lo tome sin esperar a que venza
+func (e *Elector) Stop() {
+	close(e.stop)
+	<-e.done
+	e.expire.Stop()
+
+	e.mu.Lock()
+	wasLeader := e.leader
+	e.leader = false
+	e.syncContext()
+	e.mu.Unlock()
+
+	if wasLeader {
+		if err := e.store.ReleaseLease(e.name, e.id); err != nil {
+			log.Printf("Advertencia: %v", err)
+			return
+		}
+		log.Printf("[LÍDER] Lease liberado por %s", e.id)
+	}
+}
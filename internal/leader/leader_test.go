@@ -0,0 +1,76 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowStore da el lease siempre a quien lo pide, tardando delay en responder
+type slowStore struct {
+	mu    sync.Mutex
+	delay time.Duration
+}
+
+func (s *slowStore) setDelay(delay time.Duration) {
+	s.mu.Lock()
+	s.delay = delay
+	s.mu.Unlock()
+}
+
+func (s *slowStore) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	delay := s.delay
+	s.mu.Unlock()
+	time.Sleep(delay)
+	return true, nil
+}
+
+func (s *slowStore) ReleaseLease(name, holder string) error { return nil }
+
+func (s *slowStore) LeaseHolder(name string) (string, time.Time, error) {
+	return "", time.Time{}, nil
+}
+
+// TestSlowRenewal comprueba que el vencimiento se cuenta desde antes de pedir
+// el lease y no desde la respuesta de la base de datos
+func TestSlowRenewal(t *testing.T) {
+	const ttl = 600 * time.Millisecond
+	tests := []struct {
+		name  string
+		delay time.Duration
+	}{
+		{"más de ttl/3", 300 * time.Millisecond},
+		{"más de ttl*2/3", 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &slowStore{}
+			e := New(store, "sync", "test", ttl)
+			e.parent = context.Background()
+			e.expire = time.AfterFunc(ttl, e.checkExpired)
+			defer e.expire.Stop()
+
+			e.heartbeat()
+			if !e.IsLeader() {
+				t.Fatal("no tomó el lease")
+			}
+
+			store.setDelay(tt.delay)
+			start := time.Now()
+			e.heartbeat()
+			ctx := e.Context()
+
+			// Pasado ttl*2/3 desde el pedido, el lease puede estar por vencer en
+			// la base de datos: ya no debe ser líder
+			time.Sleep(time.Until(start.Add(ttl*2/3 + 50*time.Millisecond)))
+			if e.IsLeader() {
+				t.Error("sigue siendo líder con el lease por vencer")
+			}
+			if ctx.Err() == nil {
+				t.Error("el contexto del líder no se canceló")
+			}
+		})
+	}
+}
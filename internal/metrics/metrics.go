@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"expvar"
+	"log"
+	"net/http"
+)
+
+// Métricas del proceso en formato expvar (JSON). Se publican en /debug/vars
+// si METRICS_ADDR está configurado.
+var (
+	// Reconciliación entre Discord y discord_messages (ver internal/reconcile)
+	ReconcileRuns        = expvar.NewInt("reconcile_runs")
+	ReconcileOrphans     = expvar.NewInt("reconcile_orphans_deleted")      // mensajes del bot sin registro, borrados
+	ReconcileMissing     = expvar.NewInt("reconcile_missing_rows_dropped") // registros cuyo mensaje ya no existía
+	ReconcileErrors      = expvar.NewInt("reconcile_errors")
+	ReconcileLastDrift   = expvar.NewInt("reconcile_last_drift") // huérfanos + registros sin mensaje de la última pasada
+	ReconcileLastRunUnix = expvar.NewInt("reconcile_last_run_unix")
+)
+
+// Serve publica las métricas en addr (host:puerto) en segundo plano. Un error
+// al escuchar se registra pero no detiene el bot.
+func Serve(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	go func() {
+		log.Printf("Métricas en http://%s/debug/vars", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("Advertencia: servidor de métricas detenido: %v", err)
+		}
+	}()
+}
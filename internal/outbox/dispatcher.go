@@ -0,0 +1,303 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PhelGc/furina-sync/internal/database"
+	"github.com/PhelGc/furina-sync/internal/discord"
+	"github.com/PhelGc/furina-sync/internal/schedule"
+)
+
+// ErrDeferred indica que las operaciones quedaron en espera porque se está
+// fuera del horario laboral. No es un fallo: se aplican cuando empieza.
+var ErrDeferred = errors.New("fuera del horario laboral")
+
+// MaxAttempts es cuántas veces se intenta una operación antes de darla por
+// fallida. Una operación pendiente bloquea las siguientes de su incidencia
+// (p. ej. no se borra el mensaje anterior si el reemplazo no se envió); una
+// fallida ya no.
+const MaxAttempts = 5
+
+// retention es cuánto se conservan las operaciones terminadas, para consultas
+const retention = 7 * 24 * time.Hour
+
+// Dispatcher aplica en Discord las operaciones registradas en el outbox y
+// guarda el resultado. Las operaciones se registran en la misma transacción que
+// la evaluación, así que un corte entre guardar y publicar no pierde mensajes:
+// quedan pendientes y se aplican en el siguiente Replay.
+//
+// Aplicar es idempotente: un envío reintentado busca antes en el canal un
+// mensaje con su ref (pudo enviarse justo antes del corte), y borrar un mensaje
+// que ya no existe cuenta como hecho. Dispatch puede llamarse a la vez para
+// incidencias distintas, pero no dos veces a la vez para la misma.
+//
+// Con un calendario, fuera del horario laboral las operaciones no se aplican:
+// se acumulan y salen juntas en el primer Replay dentro del horario. Los
+// resúmenes (OutboxDigest) son la excepción: tienen su propio horario.
+//
+// Con varias instancias, el contexto de Replay y Dispatch es el del líder (ver
+// leader.Elector.Context): al cancelarse no se aplica ninguna operación más, y
+// el resultado de la que estaba en curso solo se registra si la instancia
+// sigue teniendo el lease.
+type Dispatcher struct {
+	repo     database.Repository
+	discord  *discord.Client
+	calendar *schedule.Calendar // nil = sin horario
+	lease    database.Lease
+
+	mu      sync.Mutex
+	pending map[string]bool // incidencias con operaciones pendientes tras el último intento
+}
+
+// New crea el despachador. calendar puede ser nil; lease es el de la elección
+// de líder, o el valor cero si no hay.
+func New(repo database.Repository, discordClient *discord.Client, calendar *schedule.Calendar, lease database.Lease) *Dispatcher {
+	return &Dispatcher{
+		repo:     repo,
+		discord:  discordClient,
+		calendar: calendar,
+		lease:    lease,
+		pending:  make(map[string]bool),
+	}
+}
+
+// Open indica si ahora se pueden aplicar operaciones en Discord
+func (d *Dispatcher) Open() bool {
+	return d.calendar == nil || d.calendar.Open(time.Now())
+}
+
+// Replay aplica todo lo pendiente, p. ej. lo que quedó de una ejecución
+// anterior que se cortó, y depura las operaciones terminadas antiguas. Se
+// llama al empezar cada sincronización, antes de leer los mensajes de la BD.
+func (d *Dispatcher) Replay(ctx context.Context) {
+	entries, err := d.repo.PendingOutbox("")
+	if err != nil {
+		log.Printf("Advertencia: %v", err)
+		return
+	}
+	if !d.Open() {
+		var held []database.OutboxEntry
+		entries, held = deferrable(entries)
+		if len(held) > 0 {
+			d.hold(held)
+			log.Printf("Outbox: fuera del horario laboral, %d operación(es) en espera hasta %s",
+				len(held), d.calendar.NextOpen(time.Now()).Format("Mon 02/01 15:04"))
+		}
+	}
+	if len(entries) > 0 {
+		log.Printf("Outbox: %d operación(es) pendiente(s) de Discord", len(entries))
+		if _, err := d.apply(ctx, entries); err != nil {
+			log.Printf("Advertencia: outbox: %v", err)
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	if pruned, err := d.repo.PruneOutbox(time.Now().Add(-retention)); err != nil {
+		log.Printf("Advertencia: %v", err)
+	} else if pruned > 0 {
+		log.Printf("Outbox: %d operación(es) terminada(s) depurada(s)", pruned)
+	}
+}
+
+// Dispatch aplica las operaciones pendientes de una incidencia. Devuelve
+// cuántos mensajes envió y los errores de las operaciones que quedaron
+// pendientes o fallaron; ErrDeferred si está fuera del horario laboral.
+func (d *Dispatcher) Dispatch(ctx context.Context, incidentKey string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	entries, err := d.repo.PendingOutbox(incidentKey)
+	if err != nil {
+		return 0, err
+	}
+	if !d.Open() {
+		var held []database.OutboxEntry
+		entries, held = deferrable(entries)
+		d.hold(held)
+		if len(held) > 0 && len(entries) == 0 {
+			return 0, ErrDeferred
+		}
+	}
+	return d.apply(ctx, entries)
+}
+
+// deferrable separa lo que espera al horario laboral. Los resúmenes no esperan:
+// se publican a la hora de su propio horario.
+func deferrable(entries []database.OutboxEntry) (now, held []database.OutboxEntry) {
+	for _, entry := range entries {
+		if entry.Operation == database.OutboxDigest {
+			now = append(now, entry)
+		} else {
+			held = append(held, entry)
+		}
+	}
+	return now, held
+}
+
+// Pending indica si la incidencia quedó con operaciones pendientes, porque
+// fallaron o porque esperan el horario laboral. Mientras tanto no conviene
+// planificar publicaciones desde la caché: duplicarían las pendientes.
+func (d *Dispatcher) Pending(incidentKey string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pending[incidentKey]
+}
+
+// hold marca como pendientes las incidencias de entries sin aplicarlas
+func (d *Dispatcher) hold(entries []database.OutboxEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, entry := range entries {
+		d.pending[entry.IncidentKey] = true
+	}
+}
+
+// apply ejecuta las operaciones en orden. Si una falla se saltan las
+// siguientes de la misma incidencia hasta el próximo intento. Si se cancela ctx
+// o se pierde el lease, las que faltan quedan pendientes para el próximo líder.
+func (d *Dispatcher) apply(ctx context.Context, entries []database.OutboxEntry) (int, error) {
+	sent := 0
+	var errs []string
+	var stopped error
+	blocked := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		seen[entry.IncidentKey] = true
+		if stopped == nil {
+			stopped = ctx.Err()
+		}
+		if stopped != nil {
+			blocked[entry.IncidentKey] = true
+			continue
+		}
+		if blocked[entry.IncidentKey] {
+			continue
+		}
+
+		err := d.applyOne(&entry)
+		if err == nil {
+			if entry.Operation == database.OutboxSend {
+				sent++
+			}
+			continue
+		}
+		if errors.Is(err, database.ErrLeaseLost) {
+			// No es un fallo de la operación: la reintenta quien tenga el lease
+			stopped = err
+			blocked[entry.IncidentKey] = true
+			continue
+		}
+
+		final := entry.Attempts >= MaxAttempts
+		if ferr := d.repo.FailOutbox(entry.ID, err.Error(), final); ferr != nil {
+			log.Printf("Advertencia: %v", ferr)
+		}
+		if final {
+			log.Printf("Outbox: %s de %s en %s descartado tras %d intentos: %v",
+				entry.Operation, entry.IncidentKey, entry.ChannelID, entry.Attempts, err)
+		} else if entry.Operation != database.OutboxNotice {
+			// Un aviso que no sale (p. ej. sin permisos en el canal anterior) no
+			// retiene la evaluación
+			blocked[entry.IncidentKey] = true
+		}
+		errs = append(errs, fmt.Sprintf("%s %s: %v", entry.Operation, entry.IncidentKey, err))
+	}
+
+	d.mu.Lock()
+	for key := range seen {
+		d.pending[key] = blocked[key]
+	}
+	d.mu.Unlock()
+
+	if stopped != nil {
+		errs = append(errs, fmt.Sprintf("operaciones sin aplicar: %v", stopped))
+	}
+	if len(errs) > 0 {
+		return sent, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return sent, nil
+}
+
+// applyOne ejecuta una operación en Discord y registra el resultado
+func (d *Dispatcher) applyOne(entry *database.OutboxEntry) error {
+	if err := d.repo.StartOutboxAttempt(entry.ID); err != nil {
+		return err
+	}
+	entry.Attempts++
+
+	switch entry.Operation {
+	case database.OutboxSend:
+		messageID, err := d.sendOnce(entry, d.discord.SendPayload)
+		if err != nil {
+			return err
+		}
+		return d.repo.CompleteOutboxSend(*entry, messageID, d.lease)
+
+	case database.OutboxReply:
+		reminderID, err := d.sendOnce(entry, d.discord.SendReminder)
+		if err != nil {
+			return err
+		}
+		return d.repo.CompleteOutboxReminder(*entry, entry.MessageID, reminderID, d.lease)
+
+	case database.OutboxRepost:
+		messageID, err := d.sendOnce(entry, d.discord.SendReminder)
+		if err != nil {
+			return err
+		}
+		return d.repo.CompleteOutboxReminder(*entry, messageID, "", d.lease)
+
+	case database.OutboxNotice:
+		messageID, err := d.sendOnce(entry, d.discord.SendPayload)
+		if err != nil {
+			return err
+		}
+		return d.repo.CompleteOutboxNotice(*entry, messageID, d.lease)
+
+	case database.OutboxDigest:
+		messageID, err := d.sendOnce(entry, d.discord.SendDigestPayload)
+		if err != nil {
+			return err
+		}
+		return d.repo.CompleteOutboxDigest(*entry, messageID, d.lease)
+
+	case database.OutboxDelete:
+		// Las respuestas de recordatorio primero: sin el mensaje quedarían sueltas
+		reminders, err := d.repo.ReminderMessages(entry.ChannelID, entry.MessageID)
+		if err != nil {
+			return err
+		}
+		for _, reminderID := range reminders {
+			if err := d.discord.DeleteMessage(entry.ChannelID, reminderID); err != nil {
+				return err
+			}
+		}
+		if err := d.discord.DeleteMessage(entry.ChannelID, entry.MessageID); err != nil {
+			return err
+		}
+		return d.repo.CompleteOutboxDelete(*entry, d.lease)
+
+	default:
+		return fmt.Errorf("operación desconocida: %q", entry.Operation)
+	}
+}
+
+// sendOnce envía el payload de la operación con send, salvo que un intento
+// anterior ya lo haya enviado sin llegar a registrarlo. Devuelve el ID del mensaje.
+func (d *Dispatcher) sendOnce(entry *database.OutboxEntry, send func(channelID, payload string) (string, error)) (string, error) {
+	if entry.Attempts > 1 {
+		found, err := d.discord.FindByRef(entry.ChannelID, entry.Ref, entry.CreatedAt)
+		if err != nil || found != "" {
+			return found, err
+		}
+	}
+	return send(entry.ChannelID, entry.Payload)
+}
@@ -0,0 +1,201 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/PhelGc/furina-sync/internal/database"
+	"github.com/PhelGc/furina-sync/internal/discord"
+	"github.com/PhelGc/furina-sync/internal/metrics"
+	"github.com/bwmarrin/discordgo"
+)
+
+// orphanGrace protege los mensajes recién enviados: pueden pertenecer a un
+// envío que todavía no se registró (p. ej. de la instancia que era líder antes)
+const orphanGrace = 10 * time.Minute
+
+// Report resume una pasada de reconciliación
+type Report struct {
+	Channels int // canales revisados
+	Orphans  int // mensajes del bot sin registro, borrados
+	Missing  int // registros cuyo mensaje ya no existía, eliminados
+	Errors   int
+}
+
+// Drift es la cantidad de diferencias encontradas
+func (r Report) Drift() int {
+	return r.Orphans + r.Missing
+}
+
+// Reconciler compara los mensajes de evaluación que el bot tiene en Discord con
+// discord_messages y corrige las diferencias: borra los mensajes que no están
+// registrados y elimina los registros cuyo mensaje se borró a mano. Un registro
+// eliminado hace que la siguiente sincronización vuelva a publicar la
+// evaluación desde la caché.
+//
+// Como el outbox, solo corrige mientras la instancia es líder: el contexto de
+// Run es el del líder, y los registros se eliminan con el lease (ver
+// database.Lease).
+type Reconciler struct {
+	repo    database.Repository
+	discord *discord.Client
+	lease   database.Lease
+}
+
+// New crea el reconciliador. lease es el de la elección de líder, o el valor
+// cero si no hay.
+func New(repo database.Repository, discordClient *discord.Client, lease database.Lease) *Reconciler {
+	return &Reconciler{repo: repo, discord: discordClient, lease: lease}
+}
+
+// Run hace una pasada por los canales configurados y por los que tienen
+// mensajes registrados (incluidos los mensajes directos). Debe ejecutarse fuera
+// de una sincronización. Si ctx se cancela (la instancia dejó de ser líder) no
+// revisa más canales ni borra más mensajes.
+func (r *Reconciler) Run(ctx context.Context) Report {
+	var report Report
+	defer func() {
+		metrics.ReconcileRuns.Add(1)
+		metrics.ReconcileOrphans.Add(int64(report.Orphans))
+		metrics.ReconcileMissing.Add(int64(report.Missing))
+		metrics.ReconcileErrors.Add(int64(report.Errors))
+		metrics.ReconcileLastDrift.Set(int64(report.Drift()))
+		metrics.ReconcileLastRunUnix.Set(time.Now().Unix())
+	}()
+
+	rows, err := r.repo.GetAllActiveMessages()
+	if err != nil {
+		log.Printf("[RECONCILIACIÓN] Error: %v", err)
+		report.Errors++
+		return report
+	}
+	// Los envíos pendientes del outbox pueden tener ya su mensaje en Discord
+	pending, err := r.repo.PendingOutbox("")
+	if err != nil {
+		log.Printf("[RECONCILIACIÓN] Error: %v", err)
+		report.Errors++
+		return report
+	}
+	pendingRefs := make(map[string]bool, len(pending))
+	for _, entry := range pending {
+		pendingRefs[entry.Ref] = true
+	}
+
+	tracked := make(map[string]map[string]database.MessageToDelete)
+	for _, row := range rows {
+		if tracked[row.ChannelID] == nil {
+			tracked[row.ChannelID] = make(map[string]database.MessageToDelete)
+		}
+		tracked[row.ChannelID][row.MessageID] = row
+	}
+	channels := r.discord.ConfiguredChannels()
+	for channelID := range tracked {
+		if !contains(channels, channelID) {
+			channels = append(channels, channelID)
+		}
+	}
+
+	for _, channelID := range channels {
+		if ctx.Err() != nil {
+			log.Printf("[RECONCILIACIÓN] Interrumpida: %v", ctx.Err())
+			break
+		}
+		r.reconcileChannel(ctx, channelID, tracked[channelID], pendingRefs, &report)
+		report.Channels++
+	}
+
+	if report.Drift() > 0 || report.Errors > 0 {
+		log.Printf("[RECONCILIACIÓN] %d canal(es): %d mensaje(s) huérfano(s) borrado(s), %d registro(s) sin mensaje eliminado(s), %d error(es)",
+			report.Channels, report.Orphans, report.Missing, report.Errors)
+	} else {
+		log.Printf("[RECONCILIACIÓN] %d canal(es) sin diferencias", report.Channels)
+	}
+	return report
+}
+
+// reconcileChannel corrige un canal. rows son los mensajes registrados en él.
+// El historial del canal se lee una sola vez, hacia atrás hasta el registrado
+// más antiguo, y se compara con los registros.
+func (r *Reconciler) reconcileChannel(ctx context.Context, channelID string, rows map[string]database.MessageToDelete, pendingRefs map[string]bool, report *Report) {
+	since := time.Now()
+	for messageID := range rows {
+		if sent, err := discordgo.SnowflakeTimestamp(messageID); err == nil && sent.Before(since) {
+			since = sent
+		}
+	}
+	posted, err := r.discord.BotMessages(channelID, since.Add(-time.Minute))
+	if err != nil {
+		log.Printf("[RECONCILIACIÓN] Advertencia: %v", err)
+		report.Errors++
+		return
+	}
+
+	seen := make(map[string]bool, len(posted))
+	for _, message := range posted {
+		seen[message.ID] = true
+		if _, ok := rows[message.ID]; ok || !message.Evaluation {
+			continue
+		}
+		if (message.Ref != "" && pendingRefs[message.Ref]) || time.Since(message.Timestamp) < orphanGrace {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err := r.discord.DeleteMessage(channelID, message.ID); err != nil {
+			log.Printf("[RECONCILIACIÓN] Advertencia: no se pudo borrar mensaje huérfano %s: %v", message.ID, err)
+			report.Errors++
+			continue
+		}
+		log.Printf("[RECONCILIACIÓN] Mensaje huérfano borrado: %s (canal %s)", message.ID, channelID)
+		report.Orphans++
+	}
+
+	for messageID, row := range rows {
+		if seen[messageID] {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		// Las respuestas de recordatorio del mensaje borrado se borran con él
+		reminders, err := r.repo.ReminderMessages(channelID, messageID)
+		if err != nil {
+			log.Printf("[RECONCILIACIÓN] Advertencia: %v", err)
+			report.Errors++
+			continue
+		}
+		failed := false
+		for _, reminderID := range reminders {
+			if err := r.discord.DeleteMessage(channelID, reminderID); err != nil {
+				log.Printf("[RECONCILIACIÓN] Advertencia: no se pudo borrar el recordatorio %s: %v", reminderID, err)
+				report.Errors++
+				failed = true
+			}
+		}
+		if failed {
+			continue
+		}
+		if err := r.repo.DeleteMessage(row.IncidentKey, channelID, messageID, r.lease); err != nil {
+			log.Printf("[RECONCILIACIÓN] Advertencia: %v", err)
+			report.Errors++
+			if errors.Is(err, database.ErrLeaseLost) {
+				return
+			}
+			continue
+		}
+		log.Printf("[RECONCILIACIÓN] %s: el mensaje %s ya no existe en Discord, se volverá a publicar", row.IncidentKey, messageID)
+		report.Missing++
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package reload
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/PhelGc/furina-sync/internal/config"
+	"github.com/PhelGc/furina-sync/internal/discord"
+	"github.com/PhelGc/furina-sync/internal/evaluator"
+)
+
+// Reloader vigila los archivos de prompts, las reglas de ruteo y el .env y aplica los cambios en
+// caliente sobre el evaluador y el cliente de Discord, sin reiniciar el proceso.
+type Reloader struct {
+	evalClient    *evaluator.Client
+	discordClient *discord.Client
+	modTimes      map[string]time.Time
+}
+
+// New crea un Reloader a partir de la configuración con la que arrancó el proceso
+func New(evalClient *evaluator.Client, discordClient *discord.Client) *Reloader {
+	r := &Reloader{
+		evalClient:    evalClient,
+		discordClient: discordClient,
+	}
+	r.modTimes = r.snapshot()
+	return r
+}
+
+// Run revisa los archivos cada interval y escucha SIGHUP. Bloquea, por lo que
+// debe ejecutarse en su propia goroutine. Con interval 0 solo responde a SIGHUP.
+// En Windows SIGHUP nunca llega (ver "Recarga en caliente" en el README): ahí
+// el disparador es el cambio de un archivo vigilado.
+func (r *Reloader) Run(interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-hup:
+			log.Println("[RELOAD] SIGHUP recibido")
+		case <-tick:
+			current := r.snapshot()
+			if !changed(r.modTimes, current) {
+				continue
+			}
+			log.Println("[RELOAD] Cambios detectados en archivos de configuración")
+		}
+
+		if err := r.Reload(); err != nil {
+			log.Printf("[RELOAD] Cambios rechazados, se mantiene la configuración anterior: %v", err)
+		}
+		// Registrar los tiempos aunque la recarga falle para no reintentar en cada revisión
+		r.modTimes = r.snapshot()
+	}
+}
+
+// Reload lee la configuración y los prompts, los valida y solo entonces los aplica
+func (r *Reloader) Reload() error {
+	cfg, err := config.Reload()
+	if err != nil {
+		return err
+	}
+
+	prompts, err := evaluator.LoadPrompts(cfg.Eval)
+	if err != nil {
+		return err
+	}
+	router, err := discord.LoadRouter(cfg.Discord.RoutesFile, cfg.Discord.Channels)
+	if err != nil {
+		return err
+	}
+	if err := validate(cfg, prompts, router); err != nil {
+		return err
+	}
+
+	for _, line := range diffPromptSets(r.evalClient.Prompts(), prompts) {
+		log.Printf("[RELOAD] Prompts %s", line)
+	}
+	// Las rutas pueden cambiar sin que cambie ningún archivo, así que se aplica siempre
+	r.evalClient.SetPrompts(prompts)
+
+	oldChannels := r.discordClient.Channels()
+	oldRouter := r.discordClient.Router()
+	// El archivo de reglas puede cambiar sin que cambien los canales, así que se aplica siempre
+	r.discordClient.SetRouting(cfg.Discord.Channels, router)
+	for _, line := range diffChannels(oldChannels, cfg.Discord.Channels) {
+		log.Printf("[RELOAD] Canal %s", line)
+	}
+	if oldRouter.Mode != router.Mode || oldRouter.File != router.File || !reflect.DeepEqual(oldRouter.Rules, router.Rules) {
+		log.Printf("[RELOAD] Reglas de ruteo: %d (modo %s)", len(router.Rules), router.Mode)
+	}
+	// Política, canal de respaldo y usuarios: se aplican siempre, son baratos
+	r.discordClient.SetUnmapped(cfg.Discord.UnmappedPolicy, cfg.Discord.FallbackChannel, cfg.Discord.Users)
+
+	return nil
+}
+
+// validate revisa los valores recargados antes de aplicarlos
+func validate(cfg *config.Config, prompts *evaluator.PromptLoader, router *discord.Router) error {
+	for name, set := range prompts.Sets {
+		for _, phase := range set.Phases {
+			if phase.Prompt != nil && strings.TrimSpace(phase.Prompt.Text) == "" {
+				return fmt.Errorf("el prompt de la fase %s del set %s no puede estar vacío", phase.Name, name)
+			}
+		}
+	}
+	// Formatos, rangos y políticas ya los valida config.Reload; los canales de las
+	// reglas, LoadRouter. Aquí queda lo que depende de los archivos cargados.
+	if len(router.Rules) == 0 && cfg.Discord.FallbackChannel == "" {
+		return fmt.Errorf("no hay reglas de ruteo ni asignaciones en DISCORD_CHANNELS y no hay DISCORD_FALLBACK_CHANNEL")
+	}
+	return nil
+}
+
+// snapshot obtiene la fecha de modificación de los archivos vigilados
+func (r *Reloader) snapshot() map[string]time.Time {
+	times := make(map[string]time.Time)
+	paths := append([]string{config.EnvFile, config.File()}, r.evalClient.Prompts().Files()...)
+	if file := r.discordClient.Router().File; file != "" {
+		paths = append(paths, file)
+	}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			times[path] = info.ModTime()
+		}
+	}
+	return times
+}
+
+// changed indica si algún archivo vigilado apareció, desapareció o se modificó
+func changed(before, after map[string]time.Time) bool {
+	if len(before) != len(after) {
+		return true
+	}
+	for path, t := range after {
+		if !before[path].Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffPromptSets describe los sets de prompts agregados, eliminados o con nueva versión
+func diffPromptSets(before, after *evaluator.PromptLoader) []string {
+	var diff []string
+	for name, set := range after.Sets {
+		old, existed := before.Sets[name]
+		if !existed {
+			diff = append(diff, fmt.Sprintf("agregado: %s", set.Version()))
+		} else if old.Version() != set.Version() {
+			diff = append(diff, fmt.Sprintf("actualizado: %s → %s", old.Version(), set.Version()))
+		}
+	}
+	for name, set := range before.Sets {
+		if _, exists := after.Sets[name]; !exists {
+			diff = append(diff, fmt.Sprintf("eliminado: %s", set.Version()))
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
+
+// diffChannels describe las asignaciones agregadas, eliminadas o modificadas
+func diffChannels(before, after map[string]string) []string {
+	var diff []string
+	for assignee, channelID := range after {
+		old, existed := before[assignee]
+		if !existed {
+			diff = append(diff, fmt.Sprintf("agregado: %s → %s", assignee, channelID))
+		} else if old != channelID {
+			diff = append(diff, fmt.Sprintf("modificado: %s → %s (antes %s)", assignee, channelID, old))
+		}
+	}
+	for assignee, channelID := range before {
+		if _, exists := after[assignee]; !exists {
+			diff = append(diff, fmt.Sprintf("eliminado: %s (%s)", assignee, channelID))
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
@@ -0,0 +1,98 @@
+package reminder
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PhelGc/furina-sync/internal/schedule"
+)
+
+// Policy decide cuándo una incidencia con puntaje bajo que no cambió recibe un
+// recordatorio
+type Policy struct {
+	Interval   time.Duration      // tiempo sin cambios desde la última notificación
+	Max        int                // recordatorios por mensaje (0 = sin límite)
+	BelowScore int                // puntaje bajo el cual se recuerda (0 = umbral del set de prompts)
+	Mode       string             // config.ReminderReply o config.ReminderRepost
+	Quiet      Window             // horario en que no se envían recordatorios
+	Location   *time.Location     // zona de Quiet (SCHEDULE_TIMEZONE); nil = hora local
+	Calendar   *schedule.Calendar // fuera del horario laboral no hay recordatorios; nil = sin horario
+}
+
+// Due indica si corresponde un recordatorio ahora. lastNotification y count son
+// los del mensaje publicado; score es el puntaje promedio de la evaluación y
+// threshold el umbral de su set de prompts.
+func (p *Policy) Due(lastNotification time.Time, count, score, threshold int, now time.Time) bool {
+	limit := threshold
+	if p.BelowScore > 0 {
+		limit = p.BelowScore
+	}
+	switch {
+	case score >= limit:
+		return false
+	case p.Max > 0 && count >= p.Max:
+		return false
+	case now.Sub(lastNotification) < p.Interval:
+		return false
+	case p.Calendar != nil && !p.Calendar.Open(now):
+		return false
+	case p.Quiet.Contains(p.local(now)):
+		return false
+	}
+	return true
+}
+
+func (p *Policy) local(t time.Time) time.Time {
+	if p.Location == nil {
+		return t
+	}
+	return t.In(p.Location)
+}
+
+// Window es una franja horaria diaria, p. ej. 22:00-08:00. Puede
+// cruzar la medianoche. El valor cero es una franja vacía.
+type Window struct {
+	from, to int // minutos desde la medianoche
+}
+
+// ParseWindow interpreta "HH:MM-HH:MM". Una cadena vacía es una franja vacía.
+func ParseWindow(value string) (Window, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Window{}, nil
+	}
+	fromText, toText, ok := strings.Cut(value, "-")
+	if !ok {
+		return Window{}, fmt.Errorf("%q no tiene el formato HH:MM-HH:MM", value)
+	}
+	from, err := schedule.ParseClock(fromText)
+	if err != nil {
+		return Window{}, err
+	}
+	to, err := schedule.ParseClock(toText)
+	if err != nil {
+		return Window{}, err
+	}
+	return Window{from: from, to: to}, nil
+}
+
+// Contains indica si t cae dentro de la franja (el fin no se incluye)
+func (w Window) Contains(t time.Time) bool {
+	if w.from == w.to {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if w.from < w.to {
+		return minute >= w.from && minute < w.to
+	}
+	return minute >= w.from || minute < w.to
+}
+
+// String devuelve la franja en el formato de ParseWindow
+func (w Window) String() string {
+	if w.from == w.to {
+		return ""
+	}
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.from/60, w.from%60, w.to/60, w.to%60)
+}
@@ -0,0 +1,165 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+
+	"github.com/PhelGc/furina-sync/internal/discord"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Dimensiones del gráfico de tendencia, en píxeles. A la derecha va la leyenda.
+const (
+	chartWidth  = 800
+	chartHeight = 400
+	plotLeft    = 50
+	plotRight   = 640
+	plotTop     = 40
+	plotBottom  = 360
+)
+
+var (
+	chartBackground = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	chartGrid       = color.RGBA{0xDD, 0xDD, 0xDD, 0xFF}
+	chartText       = color.RGBA{0x33, 0x33, 0x33, 0xFF}
+	// Una por serie; si hay más series que colores se repiten
+	chartPalette = []color.RGBA{
+		{0x34, 0x98, 0xDB, 0xFF}, {0xE7, 0x4C, 0x3C, 0xFF}, {0x2E, 0xCC, 0x71, 0xFF}, {0xF3, 0x9C, 0x12, 0xFF},
+		{0x9B, 0x59, 0xB6, 0xFF}, {0x1A, 0xBC, 0x9C, 0xFF}, {0x34, 0x49, 0x5E, 0xFF}, {0xE9, 0x1E, 0x63, 0xFF},
+	}
+)
+
+// series es el puntaje medio de un equipo en cada período; discord.NoScore
+// deja un hueco
+type series struct {
+	name   string
+	values []int
+}
+
+// renderChart dibuja un gráfico de líneas del puntaje (0–100) por período y lo
+// devuelve en PNG. labels nombra cada período en el eje X.
+func renderChart(title string, labels []string, data []series) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, chartHeight))
+	draw.Draw(img, img.Bounds(), &image.Uniform{chartBackground}, image.Point{}, draw.Src)
+
+	drawText(img, plotLeft, 20, title, chartText)
+
+	// Eje Y: líneas cada 20 puntos
+	for score := 0; score <= 100; score += 20 {
+		y := scoreY(score)
+		line(img, plotLeft, y, plotRight, y, chartGrid, 1)
+		drawText(img, plotLeft-30, y+4, fmt.Sprintf("%3d", score), chartText)
+	}
+
+	// Eje X: una marca por período, con etiqueta si hay lugar
+	step := 1
+	if len(labels) > 0 {
+		step = (len(labels)*45)/(plotRight-plotLeft) + 1
+	}
+	for i, label := range labels {
+		x := periodX(i, len(labels))
+		line(img, x, plotBottom, x, plotBottom+4, chartText, 1)
+		if i%step == 0 || i == len(labels)-1 {
+			drawText(img, x-len(label)*7/2, plotBottom+18, label, chartText)
+		}
+	}
+
+	for i, s := range data {
+		c := chartPalette[i%len(chartPalette)]
+		prevX, prevY, hasPrev := 0, 0, false
+		for j, value := range s.values {
+			if value == discord.NoScore {
+				hasPrev = false
+				continue
+			}
+			x, y := periodX(j, len(s.values)), scoreY(value)
+			if hasPrev {
+				line(img, prevX, prevY, x, y, c, 2)
+			}
+			fill(img, image.Rect(x-3, y-3, x+4, y+4), c)
+			prevX, prevY, hasPrev = x, y, true
+		}
+
+		// Leyenda
+		legendY := plotTop + 10 + i*18
+		fill(img, image.Rect(plotRight+20, legendY-8, plotRight+30, legendY+2), c)
+		// La fuente solo tiene Latin-1: el corte se marca con un punto
+		name := strings.Replace(discord.Shorten(s.name, 20), "…", ".", 1)
+		drawText(img, plotRight+36, legendY+1, name, chartText)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("error generando gráfico: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func scoreY(score int) int {
+	return plotBottom - score*(plotBottom-plotTop)/100
+}
+
+func periodX(i, n int) int {
+	if n <= 1 {
+		return (plotLeft + plotRight) / 2
+	}
+	margin := 20
+	return plotLeft + margin + i*(plotRight-plotLeft-2*margin)/(n-1)
+}
+
+// drawText escribe con la fuente de mapa de bits 7x13; (x, y) es la línea base
+func drawText(img draw.Image, x, y int, text string, c color.Color) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+func fill(img draw.Image, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, &image.Uniform{c}, image.Point{}, draw.Src)
+}
+
+// line dibuja un segmento de width píxeles de grosor (Bresenham)
+func line(img draw.Image, x0, y0, x1, y1 int, c color.Color, width int) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		fill(img, image.Rect(x0, y0, x0+width, y0+width), c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
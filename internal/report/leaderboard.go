@@ -0,0 +1,306 @@
+package report
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PhelGc/furina-sync/internal/database"
+	"github.com/PhelGc/furina-sync/internal/discord"
+	"github.com/PhelGc/furina-sync/internal/evaluator"
+)
+
+// Campos de salida que usa el ranking. Son los de la fase de descripción del
+// pipeline por defecto; un set de prompts sin ellos simplemente no los muestra.
+var distributionFields = []string{"claridad", "causa_raiz"}
+
+const shareField = "impacto_definido"
+
+// Equipos de las incidencias que no caen en ninguno configurado
+const (
+	allTeams = "Todos"
+	noTeam   = "Sin equipo"
+)
+
+// leaderboardPayload arma el ranking de [from, to) con el gráfico de la media de
+// descripción de cada equipo en los últimos Periods períodos. Devuelve el
+// payload para el outbox y cuántas incidencias se evaluaron en el período.
+func (r *Reporter) leaderboardPayload(digest Digest, from, to time.Time, ref string) (string, int, error) {
+	start := to.Add(-time.Duration(digest.Periods) * digest.Lookback)
+	if from.Before(start) {
+		start = from
+	}
+	entries, err := r.repo.EvaluationHistory(start, to)
+	if err != nil {
+		return "", 0, err
+	}
+
+	current := described(latest(between(entries, from, to), digest.Members))
+	board := buildLeaderboard(digest, current)
+	board.From, board.To = from.In(r.location), to.In(r.location)
+
+	// Tendencia: la media de descripción de cada equipo en períodos iguales hasta ahora
+	var labels []string
+	trend := make(map[string][]int)
+	var order []string
+	for i := digest.Periods; i > 0; i-- {
+		bucketEnd := to.Add(-time.Duration(i-1) * digest.Lookback)
+		bucket := described(latest(between(entries, bucketEnd.Add(-digest.Lookback), bucketEnd), digest.Members))
+		labels = append(labels, bucketEnd.In(r.location).Format("02/01"))
+		for team, evals := range groupByTeam(digest, bucket) {
+			if trend[team] == nil {
+				trend[team] = make([]int, digest.Periods)
+				for j := range trend[team] {
+					trend[team][j] = discord.NoScore
+				}
+				order = append(order, team)
+			}
+			trend[team][digest.Periods-i] = scoreRow("", evals, digest.Percentile).Mean
+		}
+	}
+	sort.Strings(order)
+	data := make([]series, len(order))
+	for i, team := range order {
+		data[i] = series{name: team, values: trend[team]}
+	}
+
+	chart, err := renderChart("Puntaje medio de descripción por equipo", labels, data)
+	if err != nil {
+		log.Printf("[RESUMEN] Advertencia: %s se publica sin gráfico: %v", digest.Name, err)
+	}
+	payload, err := r.discord.LeaderboardPayload(board, chart, ref)
+	return payload, len(current), err
+}
+
+// described deja las evaluaciones con la fase de descripción (la primera del
+// pipeline) ejecutada y toma su puntaje: el ranking mide la calidad de lo que
+// escribe el assignee, no el promedio de todas las fases
+func described(evals []evaluation) []evaluation {
+	result := make([]evaluation, 0, len(evals))
+	for _, e := range evals {
+		if len(e.phases) == 0 || !e.phases[0].Scored() {
+			continue
+		}
+		e.score = e.phases[0].Score
+		result = append(result, e)
+	}
+	return result
+}
+
+// between filtra las entradas evaluadas en [from, to)
+func between(entries []database.HistoryEntry, from, to time.Time) []database.HistoryEntry {
+	var result []database.HistoryEntry
+	for _, entry := range entries {
+		if !entry.EvaluatedAt.Before(from) && entry.EvaluatedAt.Before(to) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// buildLeaderboard calcula las estadísticas por equipo y por assignee y la
+// distribución de los campos categóricos por equipo y por assignee
+func buildLeaderboard(digest Digest, current []evaluation) *discord.Leaderboard {
+	board := &discord.Leaderboard{Title: digest.Title, Percentile: digest.Percentile}
+
+	teams := groupByTeam(digest, current)
+	teamNames := make([]string, 0, len(teams))
+	for team := range teams {
+		teamNames = append(teamNames, team)
+	}
+	sort.Strings(teamNames)
+	for _, team := range teamNames {
+		board.Teams = append(board.Teams, scoreRow(team, teams[team], digest.Percentile))
+	}
+	sortRows(board.Teams)
+
+	byAssignee := make(map[string][]evaluation)
+	names := make(map[string]string)
+	for _, e := range current {
+		id := firstNonEmpty(e.AssigneeID, e.Assignee)
+		byAssignee[id] = append(byAssignee[id], e)
+		names[id] = firstNonEmpty(e.Assignee, "Sin assignee")
+	}
+	assigneeIDs := make([]string, 0, len(byAssignee))
+	for id := range byAssignee {
+		assigneeIDs = append(assigneeIDs, id)
+		board.Assignees = append(board.Assignees, scoreRow(names[id], byAssignee[id], digest.Percentile))
+	}
+	sortRows(board.Assignees)
+	sort.Slice(assigneeIDs, func(i, j int) bool {
+		if names[assigneeIDs[i]] != names[assigneeIDs[j]] {
+			return names[assigneeIDs[i]] < names[assigneeIDs[j]]
+		}
+		return assigneeIDs[i] < assigneeIDs[j]
+	})
+
+	for _, e := range current {
+		if out, ok := output(e, shareField); ok {
+			board.ShareLabel = out.Label
+			break
+		}
+	}
+
+	for _, key := range distributionFields {
+		counter := newValueCounter(key)
+		dist := discord.Distribution{}
+		for _, team := range teamNames {
+			if counts := counter.count(teams[team]); len(counts) > 0 {
+				dist.Rows = append(dist.Rows, discord.DistributionRow{Name: team, Values: sortCounts(counts)})
+			}
+		}
+		for _, id := range assigneeIDs {
+			if counts := counter.count(byAssignee[id]); len(counts) > 0 {
+				dist.Assignees = append(dist.Assignees, discord.DistributionRow{Name: names[id], Values: sortCounts(counts)})
+			}
+		}
+		dist.Label = counter.label
+		if len(dist.Rows) > 0 {
+			board.Distributions = append(board.Distributions, dist)
+		}
+	}
+	return board
+}
+
+// valueCounter cuenta los valores de un campo categórico. "Alta" y "alta" son
+// el mismo valor: se muestra como lo escribió la primera evaluación.
+type valueCounter struct {
+	key   string
+	label string
+	shown map[string]string // valor en minúsculas -> cómo se muestra
+}
+
+func newValueCounter(key string) *valueCounter {
+	return &valueCounter{key: key, shown: make(map[string]string)}
+}
+
+// count devuelve cuántas evaluaciones tienen cada valor del campo
+func (c *valueCounter) count(evals []evaluation) map[string]int {
+	counts := make(map[string]int)
+	for _, e := range evals {
+		out, ok := output(e, c.key)
+		if !ok {
+			continue
+		}
+		c.label = out.Label
+		value := strings.TrimSpace(fmt.Sprint(out.Value))
+		normalized := strings.ToLower(value)
+		if _, ok := c.shown[normalized]; !ok {
+			c.shown[normalized] = value
+		}
+		counts[c.shown[normalized]]++
+	}
+	return counts
+}
+
+// groupByTeam reparte las evaluaciones por equipo. Sin equipos configurados va
+// todo a uno solo; con un resumen de equipo, todo a ese equipo.
+func groupByTeam(digest Digest, evals []evaluation) map[string][]evaluation {
+	groups := make(map[string][]evaluation)
+	if len(digest.Teams) == 0 {
+		if len(evals) > 0 {
+			groups[allTeams] = evals
+		}
+		return groups
+	}
+
+	names := make([]string, 0, len(digest.Teams))
+	for team := range digest.Teams {
+		names = append(names, team)
+	}
+	sort.Strings(names)
+	for _, e := range evals {
+		team := noTeam
+		for _, name := range names {
+			if isMember(digest.Teams[name], e.HistoryEntry) {
+				team = name
+				break
+			}
+		}
+		groups[team] = append(groups[team], e)
+	}
+	return groups
+}
+
+// scoreRow calcula media, mediana y percentil del puntaje y la proporción de
+// incidencias con shareField en true
+func scoreRow(name string, evals []evaluation, percentile int) discord.LeaderboardRow {
+	row := discord.LeaderboardRow{Name: name, Incidents: len(evals), Share: discord.NoScore}
+	if len(evals) == 0 {
+		row.Mean, row.Median, row.Percentile = discord.NoScore, discord.NoScore, discord.NoScore
+		return row
+	}
+
+	scores := make([]int, len(evals))
+	sum, withShare, yes := 0, 0, 0
+	for i, e := range evals {
+		scores[i] = e.score
+		sum += e.score
+		if out, ok := output(e, shareField); ok {
+			withShare++
+			if v, _ := out.Value.(bool); v {
+				yes++
+			}
+		}
+	}
+	sort.Ints(scores)
+
+	row.Mean = sum / len(scores)
+	if n := len(scores); n%2 == 1 {
+		row.Median = scores[n/2]
+	} else {
+		row.Median = (scores[n/2-1] + scores[n/2]) / 2
+	}
+	// Percentil por rango más cercano: el menor puntaje con al menos el p% de los datos por debajo o igual
+	rank := (percentile*len(scores) + 99) / 100
+	row.Percentile = scores[rank-1]
+	if withShare > 0 {
+		row.Share = yes * 100 / withShare
+	}
+	return row
+}
+
+// sortRows ordena del mejor al peor promedio
+func sortRows(rows []discord.LeaderboardRow) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Mean != rows[j].Mean {
+			return rows[i].Mean > rows[j].Mean
+		}
+		if rows[i].Median != rows[j].Median {
+			return rows[i].Median > rows[j].Median
+		}
+		return rows[i].Name < rows[j].Name
+	})
+}
+
+// sortCounts ordena los valores de más a menos frecuente
+func sortCounts(counts map[string]int) []discord.ValueCount {
+	values := make([]discord.ValueCount, 0, len(counts))
+	for value, count := range counts {
+		values = append(values, discord.ValueCount{Value: value, Count: count})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value < values[j].Value
+	})
+	return values
+}
+
+// output busca un campo de salida en las fases ejecutadas de la evaluación
+func output(e evaluation, key string) (evaluator.OutputValue, bool) {
+	for _, phase := range e.phases {
+		if !phase.Scored() {
+			continue
+		}
+		for _, out := range phase.Outputs {
+			if out.Key == key && out.Value != nil {
+				return out, true
+			}
+		}
+	}
+	return evaluator.OutputValue{}, false
+}
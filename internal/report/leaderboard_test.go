@@ -0,0 +1,90 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/PhelGc/furina-sync/internal/database"
+	"github.com/PhelGc/furina-sync/internal/discord"
+	"github.com/PhelGc/furina-sync/internal/evaluator"
+)
+
+// scored arma una evaluación con puntaje en la fase de descripción y los
+// campos de salida indicados
+func scored(assignee string, score int, outputs ...evaluator.OutputValue) evaluation {
+	return evaluation{
+		HistoryEntry: database.HistoryEntry{IncidentKey: "INC", AssigneeID: assignee, Assignee: assignee},
+		phases:       []*evaluator.PhaseResult{{Name: "descripcion", Score: score, Outputs: outputs}},
+		score:        score,
+	}
+}
+
+func impact(v bool) evaluator.OutputValue {
+	return evaluator.OutputValue{Key: shareField, Label: "Impacto", Value: v}
+}
+
+func clarity(v string) evaluator.OutputValue {
+	return evaluator.OutputValue{Key: "claridad", Label: "Claridad", Value: v}
+}
+
+// TestScoreRow calcula media, mediana, percentil por rango más cercano y proporción
+func TestScoreRow(t *testing.T) {
+	tests := []struct {
+		name       string
+		evals      []evaluation
+		percentile int
+		want       discord.LeaderboardRow
+	}{
+		{"sin evaluaciones", nil, 90,
+			discord.LeaderboardRow{Mean: discord.NoScore, Median: discord.NoScore, Percentile: discord.NoScore, Share: discord.NoScore}},
+		{"una", []evaluation{scored("a", 70)}, 90,
+			discord.LeaderboardRow{Incidents: 1, Mean: 70, Median: 70, Percentile: 70, Share: discord.NoScore}},
+		{"cantidad impar", []evaluation{scored("a", 80), scored("a", 40), scored("a", 60)}, 50,
+			discord.LeaderboardRow{Incidents: 3, Mean: 60, Median: 60, Percentile: 60, Share: discord.NoScore}},
+		{"cantidad par", []evaluation{scored("a", 90), scored("a", 40), scored("a", 70), scored("a", 50)}, 50,
+			discord.LeaderboardRow{Incidents: 4, Mean: 62, Median: 60, Percentile: 50, Share: discord.NoScore}},
+		{"percentil 1", []evaluation{scored("a", 90), scored("a", 40), scored("a", 70), scored("a", 50)}, 1,
+			discord.LeaderboardRow{Incidents: 4, Mean: 62, Median: 60, Percentile: 40, Share: discord.NoScore}},
+		{"percentil 100", []evaluation{scored("a", 90), scored("a", 40), scored("a", 70), scored("a", 50)}, 100,
+			discord.LeaderboardRow{Incidents: 4, Mean: 62, Median: 60, Percentile: 90, Share: discord.NoScore}},
+		{"percentil 90 de 10", []evaluation{
+			scored("a", 10), scored("a", 20), scored("a", 30), scored("a", 40), scored("a", 50),
+			scored("a", 60), scored("a", 70), scored("a", 80), scored("a", 90), scored("a", 100)}, 90,
+			discord.LeaderboardRow{Incidents: 10, Mean: 55, Median: 55, Percentile: 90, Share: discord.NoScore}},
+		// La proporción solo cuenta las evaluaciones que tienen el campo
+		{"proporción", []evaluation{scored("a", 50, impact(true)), scored("a", 50, impact(false)), scored("a", 50, impact(true)), scored("a", 50)}, 90,
+			discord.LeaderboardRow{Incidents: 4, Mean: 50, Median: 50, Percentile: 50, Share: 66}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scoreRow("", tt.evals, tt.percentile)
+			if got != tt.want {
+				t.Errorf("scoreRow = %+v, se esperaba %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildLeaderboardDistributions reparte los valores por equipo y por
+// assignee sin distinguir mayúsculas
+func TestBuildLeaderboardDistributions(t *testing.T) {
+	digest := Digest{Percentile: 90}
+	board := buildLeaderboard(digest, []evaluation{
+		scored("Luis", 80, clarity("Alta")),
+		scored("Ana", 60, clarity("alta ")),
+		scored("Ana", 40, clarity("Baja")),
+		scored("Ana", 50),
+	})
+
+	want := []discord.Distribution{{
+		Label: "Claridad",
+		Rows:  []discord.DistributionRow{{Name: allTeams, Values: []discord.ValueCount{{Value: "Alta", Count: 2}, {Value: "Baja", Count: 1}}}},
+		Assignees: []discord.DistributionRow{
+			{Name: "Ana", Values: []discord.ValueCount{{Value: "Alta", Count: 1}, {Value: "Baja", Count: 1}}},
+			{Name: "Luis", Values: []discord.ValueCount{{Value: "Alta", Count: 1}}},
+		},
+	}}
+	if !reflect.DeepEqual(board.Distributions, want) {
+		t.Errorf("distribuciones:\n%+v\nse esperaba\n%+v", board.Distributions, want)
+	}
+}
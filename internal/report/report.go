@@ -0,0 +1,354 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PhelGc/furina-sync/internal/config"
+	"github.com/PhelGc/furina-sync/internal/database"
+	"github.com/PhelGc/furina-sync/internal/discord"
+	"github.com/PhelGc/furina-sync/internal/evaluator"
+	"github.com/PhelGc/furina-sync/internal/outbox"
+	"github.com/PhelGc/furina-sync/internal/schedule"
+)
+
+// Digest es un resumen periódico configurado (ver config.DigestConfig)
+type Digest struct {
+	Name       string // identifica sus publicaciones en digest_runs
+	Kind       string // config.DigestSummary o config.DigestLeaderboard
+	Title      string
+	Channel    string
+	Spec       schedule.Spec       // cuándo se publica
+	Lookback   time.Duration       // período del primer resumen y de cada punto del gráfico
+	Members    []string            // accountIds o nombres visibles del equipo; vacío = todas las incidencias
+	Teams      map[string][]string // equipos que compara el ranking
+	Worst      int                 // resumen: peores incidencias listadas
+	Percentile int                 // ranking: percentil del puntaje
+	Periods    int                 // ranking: períodos en el gráfico de tendencia
+}
+
+// Reporter publica los resúmenes de evaluaciones. Cada resumen cubre lo
+// evaluado desde el anterior y se compara con el período de ese anterior.
+//
+// Los resúmenes salen por el outbox, como las evaluaciones: el período se
+// registra junto con la operación que lo envía, así que un corte o un error
+// después de registrarlo no lo publica dos veces, y una instancia que perdió
+// el lease no lo registra.
+type Reporter struct {
+	repo     database.Repository
+	discord  *discord.Client
+	outbox   *outbox.Dispatcher
+	lease    database.Lease
+	location *time.Location
+	digests  []Digest
+}
+
+// New crea el reporter. location es la zona horaria en que se muestran las
+// fechas; lease es el de la elección de líder, o el valor cero si no hay.
+func New(repo database.Repository, discordClient *discord.Client, dispatcher *outbox.Dispatcher, lease database.Lease, location *time.Location, digests []Digest) *Reporter {
+	return &Reporter{
+		repo:     repo,
+		discord:  discordClient,
+		outbox:   dispatcher,
+		lease:    lease,
+		location: location,
+		digests:  digests,
+	}
+}
+
+// RunDue publica los resúmenes cuyo horario llegó: el siguiente horario después
+// del último publicado (o de la primera vez que se vio el resumen, si nunca se
+// publicó) ya pasó. Si el bot estuvo detenido varios períodos se publica un
+// solo resumen con todo lo pendiente. ctx es el del líder: cancelado, no se
+// publica nada más.
+func (r *Reporter) RunDue(ctx context.Context, now time.Time) {
+	for _, digest := range r.digests {
+		if ctx.Err() != nil {
+			return
+		}
+		last, err := r.repo.LastDigestRun(digest.Name)
+		if err != nil {
+			log.Printf("[RESUMEN] Error: %v", err)
+			continue
+		}
+
+		from, previousFrom := now.Add(-digest.Lookback), now.Add(-2*digest.Lookback)
+		if last != nil {
+			from, previousFrom = last.PeriodEnd, last.PeriodStart
+		}
+		since, err := r.since(digest, last, now)
+		if err != nil {
+			log.Printf("[RESUMEN] Error: %v", err)
+			continue
+		}
+		if digest.Spec.Next(since).After(now) {
+			continue
+		}
+
+		if err := r.publish(ctx, digest, previousFrom, from, now); err != nil {
+			log.Printf("[RESUMEN] Error publicando %s: %v", digest.Name, err)
+		}
+	}
+}
+
+// NextDue devuelve cuándo le toca al próximo resumen, para programar una
+// revisión en ese momento aunque no coincida con una sincronización. Devuelve
+// la hora cero si no hay resúmenes o no se pudo consultar el último publicado.
+func (r *Reporter) NextDue() time.Time {
+	var next time.Time
+	now := time.Now()
+	for _, digest := range r.digests {
+		last, err := r.repo.LastDigestRun(digest.Name)
+		if err != nil {
+			log.Printf("[RESUMEN] Error: %v", err)
+			continue
+		}
+		since, err := r.since(digest, last, now)
+		if err != nil {
+			log.Printf("[RESUMEN] Error: %v", err)
+			continue
+		}
+		if due := digest.Spec.Next(since); next.IsZero() || due.Before(next) {
+			next = due
+		}
+	}
+	return next
+}
+
+// since es desde cuándo se cuenta el próximo horario del resumen: el fin del
+// último publicado o, si nunca se publicó, la primera vez que se vio (guardada
+// en la base de datos para que un reinicio no lo postergue)
+func (r *Reporter) since(digest Digest, last *database.DigestRun, now time.Time) (time.Time, error) {
+	if last != nil {
+		return last.PeriodEnd, nil
+	}
+	return r.repo.DigestStart(digest.Name, now)
+}
+
+// publish registra el resumen de [from, to) en el outbox y lo envía
+func (r *Reporter) publish(ctx context.Context, digest Digest, previousFrom, from, to time.Time) error {
+	ref := database.NewOutboxRef()
+	var payload string
+	var evaluated int
+	var err error
+	switch digest.Kind {
+	case config.DigestLeaderboard:
+		payload, evaluated, err = r.leaderboardPayload(digest, from, to, ref)
+	default:
+		payload, evaluated, err = r.summaryPayload(digest, previousFrom, from, to, ref)
+	}
+	if err != nil {
+		return err
+	}
+
+	entry := database.OutboxEntry{
+		Ref:         ref,
+		IncidentKey: outboxKey(digest),
+		Operation:   database.OutboxDigest,
+		ChannelID:   digest.Channel,
+		Payload:     payload,
+	}
+	run := database.DigestRun{
+		Name:        digest.Name,
+		ChannelID:   digest.Channel,
+		PeriodStart: from,
+		PeriodEnd:   to,
+		Evaluated:   evaluated,
+	}
+	if err := r.repo.EnqueueDigest(run, entry, r.lease); err != nil {
+		return err
+	}
+
+	// Si no sale ahora lo reintenta el Replay de la siguiente sincronización
+	if _, err := r.outbox.Dispatch(ctx, entry.IncidentKey); err != nil {
+		return fmt.Errorf("queda pendiente en el outbox: %v", err)
+	}
+	log.Printf("[RESUMEN] %s publicado en %s: %d incidencia(s) evaluada(s)", digest.Name, digest.Channel, evaluated)
+	return nil
+}
+
+// outboxKey agrupa en el outbox las operaciones de un resumen, aparte de las
+// de las incidencias
+func outboxKey(digest Digest) string {
+	return "resumen:" + digest.Name
+}
+
+// summaryPayload arma el resumen de [from, to), comparado con [previousFrom, from)
+func (r *Reporter) summaryPayload(digest Digest, previousFrom, from, to time.Time, ref string) (string, int, error) {
+	entries, err := r.repo.EvaluationHistory(previousFrom, to)
+	if err != nil {
+		return "", 0, err
+	}
+	var current, previous []database.HistoryEntry
+	for _, entry := range entries {
+		if entry.EvaluatedAt.Before(from) {
+			previous = append(previous, entry)
+		} else {
+			current = append(current, entry)
+		}
+	}
+
+	summary := build(digest, latest(current, digest.Members), latest(previous, digest.Members))
+	summary.From, summary.To = from.In(r.location), to.In(r.location)
+
+	payload, err := r.discord.DigestPayload(summary, ref)
+	return payload, len(summary.Incidents), err
+}
+
+// evaluation es la última evaluación de una incidencia en un período
+type evaluation struct {
+	database.HistoryEntry
+	phases []*evaluator.PhaseResult
+	score  int
+}
+
+// latest se queda con la última evaluación con puntaje de cada incidencia de
+// los integrantes (todas si members está vacío), ordenadas por key
+func latest(entries []database.HistoryEntry, members []string) []evaluation {
+	byKey := make(map[string]evaluation)
+	for _, entry := range entries {
+		if len(members) > 0 && !isMember(members, entry) {
+			continue
+		}
+		var phases []*evaluator.PhaseResult
+		if err := json.Unmarshal([]byte(entry.PhaseResults), &phases); err != nil {
+			log.Printf("[RESUMEN] Advertencia: fases ilegibles en el historial de %s: %v", entry.IncidentKey, err)
+			continue
+		}
+		result := evaluator.EvaluationResult{Phases: phases}
+		if len(result.Scored()) == 0 {
+			continue
+		}
+		// El historial viene ordenado por fecha: la última pisa a las anteriores
+		byKey[entry.IncidentKey] = evaluation{HistoryEntry: entry, phases: phases, score: result.AverageScore()}
+	}
+
+	evaluations := make([]evaluation, 0, len(byKey))
+	for _, e := range byKey {
+		evaluations = append(evaluations, e)
+	}
+	sort.Slice(evaluations, func(i, j int) bool { return evaluations[i].IncidentKey < evaluations[j].IncidentKey })
+	return evaluations
+}
+
+// isMember busca el assignee por accountId o, para equipos escritos con
+// nombres, por nombre visible
+func isMember(members []string, entry database.HistoryEntry) bool {
+	for _, member := range members {
+		if (entry.AssigneeID != "" && member == entry.AssigneeID) || (entry.Assignee != "" && strings.EqualFold(member, entry.Assignee)) {
+			return true
+		}
+	}
+	return false
+}
+
+// average acumula puntajes
+type average struct{ sum, n int }
+
+func (a *average) add(score int) {
+	a.sum += score
+	a.n++
+}
+
+func (a average) value() int {
+	if a.n == 0 {
+		return discord.NoScore
+	}
+	return a.sum / a.n
+}
+
+// assigneeStats son los acumulados de un assignee en el período
+type assigneeStats struct {
+	name     string
+	count    int
+	phases   [2]average
+	score    average
+	previous average
+}
+
+// build calcula el resumen a partir de las últimas evaluaciones del período y
+// del período anterior. Fase 1 y fase 2 son las dos primeras fases del
+// pipeline; las que no se ejecutaron no cuentan en el promedio.
+func build(digest Digest, current, previous []evaluation) *discord.Digest {
+	summary := &discord.Digest{
+		Title:       digest.Title,
+		PhaseLabels: [2]string{"Fase 1", "Fase 2"},
+	}
+
+	var total, totalPrevious average
+	stats := make(map[string]*assigneeStats)
+	statsFor := func(e evaluation) *assigneeStats {
+		id := firstNonEmpty(e.AssigneeID, e.Assignee)
+		s := stats[id]
+		if s == nil {
+			s = &assigneeStats{name: firstNonEmpty(e.Assignee, "Sin assignee")}
+			stats[id] = s
+		}
+		return s
+	}
+
+	labeled := [2]bool{}
+	for _, e := range current {
+		s := statsFor(e)
+		s.count++
+		s.score.add(e.score)
+		total.add(e.score)
+		for i := 0; i < 2 && i < len(e.phases); i++ {
+			if !labeled[i] && e.phases[i].Label != "" {
+				summary.PhaseLabels[i], labeled[i] = e.phases[i].Label, true
+			}
+			if e.phases[i].Scored() {
+				s.phases[i].add(e.phases[i].Score)
+			}
+		}
+		summary.Incidents = append(summary.Incidents, discord.DigestIncident{
+			Key:      e.IncidentKey,
+			Title:    e.Title,
+			Assignee: s.name,
+			Score:    e.score,
+		})
+	}
+	for _, e := range previous {
+		totalPrevious.add(e.score)
+		// Solo interesa la tendencia de quien tiene evaluaciones en este período
+		if s := stats[firstNonEmpty(e.AssigneeID, e.Assignee)]; s != nil {
+			s.previous.add(e.score)
+		}
+	}
+	summary.Average, summary.Previous = total.value(), totalPrevious.value()
+
+	for _, s := range stats {
+		summary.Assignees = append(summary.Assignees, discord.DigestAssignee{
+			Name:      s.name,
+			Incidents: s.count,
+			Phases:    [2]int{s.phases[0].value(), s.phases[1].value()},
+			Average:   s.score.value(),
+			Previous:  s.previous.value(),
+		})
+	}
+	sort.Slice(summary.Assignees, func(i, j int) bool {
+		return strings.ToLower(summary.Assignees[i].Name) < strings.ToLower(summary.Assignees[j].Name)
+	})
+
+	worst := append([]discord.DigestIncident(nil), summary.Incidents...)
+	sort.SliceStable(worst, func(i, j int) bool { return worst[i].Score < worst[j].Score })
+	if len(worst) > digest.Worst {
+		worst = worst[:digest.Worst]
+	}
+	summary.Worst = worst
+	return summary
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
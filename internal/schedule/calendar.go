@@ -0,0 +1,172 @@
+package schedule
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	// Zonas horarias incluidas en el binario: Windows no trae la base IANA
+	_ "time/tzdata"
+)
+
+// Calendar es el horario laboral: días de la semana, franja horaria y feriados,
+// en una zona horaria. Fuera de él las entregas a Discord esperan.
+type Calendar struct {
+	Location *time.Location
+	days     [7]bool
+	from, to int             // minutos desde la medianoche; to > from
+	holidays map[string]bool // fechas YYYY-MM-DD
+}
+
+// NewCalendar arma un calendario. days es una lista de días o rangos en inglés
+// abreviado ("mon-fri", "mon,wed,fri"), hours una franja "HH:MM-HH:MM" que no
+// cruza la medianoche y holidaysFile un archivo de feriados (opcional, ver
+// LoadHolidays). timezone es un nombre IANA (America/Bogota); vacío = hora local.
+func NewCalendar(timezone, days, hours, holidaysFile string) (*Calendar, error) {
+	loc, err := LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+	c := &Calendar{Location: loc, holidays: map[string]bool{}}
+
+	if c.days, err = parseDays(days); err != nil {
+		return nil, err
+	}
+
+	fromText, toText, ok := strings.Cut(hours, "-")
+	if !ok {
+		return nil, fmt.Errorf("horario %q no tiene el formato HH:MM-HH:MM", hours)
+	}
+	if c.from, err = ParseClock(fromText); err != nil {
+		return nil, err
+	}
+	if c.to, err = ParseClock(toText); err != nil {
+		return nil, err
+	}
+	if c.to <= c.from {
+		return nil, fmt.Errorf("horario %q: el fin debe ser posterior al inicio", hours)
+	}
+
+	if holidaysFile != "" {
+		if c.holidays, err = LoadHolidays(holidaysFile); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// LoadLocation carga una zona horaria IANA; vacío = hora local
+func LoadLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("zona horaria %q desconocida", timezone)
+	}
+	return loc, nil
+}
+
+// LoadHolidays lee un archivo de feriados: una fecha YYYY-MM-DD por línea,
+// opcionalmente seguida de una descripción. Las líneas vacías y las que
+// empiezan con '#' se ignoran.
+func LoadHolidays(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo feriados: %v", err)
+	}
+	defer file.Close()
+
+	holidays := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		date := strings.Fields(line)[0]
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return nil, fmt.Errorf("%s:%d: %q no es una fecha YYYY-MM-DD", path, n, date)
+		}
+		holidays[date] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error leyendo feriados: %v", err)
+	}
+	return holidays, nil
+}
+
+// Open indica si t cae dentro del horario laboral
+func (c *Calendar) Open(t time.Time) bool {
+	t = t.In(c.Location)
+	if !c.workday(t) {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	return minute >= c.from && minute < c.to
+}
+
+// NextOpen devuelve el próximo momento dentro del horario laboral a partir de
+// t (t mismo si ya lo está). Busca hasta un año adelante.
+func (c *Calendar) NextOpen(t time.Time) time.Time {
+	if c.Open(t) {
+		return t
+	}
+	t = t.In(c.Location)
+	for i := 0; i < 366; i++ {
+		// Con time.Date y no sumando minutos a la medianoche: el día del cambio
+		// de horario de verano la medianoche más c.from no es la hora de inicio
+		start := time.Date(t.Year(), t.Month(), t.Day()+i, c.from/60, c.from%60, 0, 0, c.Location)
+		if start.After(t) && c.workday(start) {
+			return start
+		}
+	}
+	return t
+}
+
+func (c *Calendar) workday(t time.Time) bool {
+	return c.days[t.Weekday()] && !c.holidays[t.Format("2006-01-02")]
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseDays interpreta "mon-fri" o "mon,wed,fri". Un rango puede cruzar el
+// domingo ("sat-mon").
+func parseDays(value string) ([7]bool, error) {
+	var days [7]bool
+	for _, part := range strings.Split(strings.ToLower(value), ",") {
+		part = strings.TrimSpace(part)
+		fromText, toText, isRange := strings.Cut(part, "-")
+		from, ok := weekdays[strings.TrimSpace(fromText)]
+		if !ok {
+			return days, fmt.Errorf("día %q desconocido (sun, mon, tue, wed, thu, fri, sat)", fromText)
+		}
+		to := from
+		if isRange {
+			if to, ok = weekdays[strings.TrimSpace(toText)]; !ok {
+				return days, fmt.Errorf("día %q desconocido (sun, mon, tue, wed, thu, fri, sat)", toText)
+			}
+		}
+		for d := from; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == to {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// ParseClock interpreta una hora "HH:MM" y devuelve los minutos desde la medianoche
+func ParseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%q no es una hora HH:MM", strings.TrimSpace(value))
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
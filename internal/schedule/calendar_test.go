@@ -0,0 +1,66 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+// TestNextOpen calcula el próximo inicio del horario laboral
+func TestNextOpen(t *testing.T) {
+	tests := []struct {
+		name     string
+		days     string
+		hours    string
+		timezone string
+		holidays []string
+		at       time.Time
+		want     time.Time
+	}{
+		{"dentro del horario", "mon-fri", "09:00-18:00", "America/Bogota", nil,
+			time.Date(2024, 6, 3, 10, 15, 0, 0, bogota), time.Date(2024, 6, 3, 10, 15, 0, 0, bogota)},
+		{"antes de abrir", "mon-fri", "09:00-18:00", "America/Bogota", nil,
+			time.Date(2024, 6, 3, 7, 0, 0, 0, bogota), time.Date(2024, 6, 3, 9, 0, 0, 0, bogota)},
+		{"al cerrar", "mon-fri", "09:00-18:00", "America/Bogota", nil,
+			time.Date(2024, 6, 3, 18, 0, 0, 0, bogota), time.Date(2024, 6, 4, 9, 0, 0, 0, bogota)},
+		{"viernes por la noche", "mon-fri", "09:00-18:00", "America/Bogota", nil,
+			time.Date(2024, 6, 7, 20, 0, 0, 0, bogota), time.Date(2024, 6, 10, 9, 0, 0, 0, bogota)},
+		{"feriado el lunes", "mon-fri", "09:00-18:00", "America/Bogota", []string{"2024-06-10"},
+			time.Date(2024, 6, 8, 12, 0, 0, 0, bogota), time.Date(2024, 6, 11, 9, 0, 0, 0, bogota)},
+		{"rango que cruza el domingo", "sat-mon", "09:30-12:00", "America/Bogota", nil,
+			time.Date(2024, 6, 4, 10, 0, 0, 0, bogota), time.Date(2024, 6, 8, 9, 30, 0, 0, bogota)},
+		{"en otra zona", "mon-fri", "09:00-18:00", "America/Bogota", nil,
+			time.Date(2024, 6, 3, 13, 0, 0, 0, time.UTC), time.Date(2024, 6, 3, 9, 0, 0, 0, bogota)}, // 08:00 en Bogotá
+		{"día del cambio de DST", "sun", "09:00-17:00", "America/New_York", nil,
+			time.Date(2024, 3, 9, 20, 0, 0, 0, newYork), time.Date(2024, 3, 10, 9, 0, 0, 0, newYork)},
+		{"sin días hábiles en un año", "mon", "09:00-18:00", "America/Bogota", datesFrom(time.Date(2024, 6, 4, 0, 0, 0, 0, bogota), 400),
+			time.Date(2024, 6, 4, 10, 0, 0, 0, bogota), time.Date(2024, 6, 4, 10, 0, 0, 0, bogota)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCalendar(tt.timezone, tt.days, tt.hours, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, date := range tt.holidays {
+				c.holidays[date] = true
+			}
+
+			got := c.NextOpen(tt.at)
+			if !got.Equal(tt.want) {
+				t.Errorf("NextOpen(%s) = %s, se esperaba %s", tt.at, got.In(c.Location), tt.want)
+			}
+			if !got.Equal(tt.at) && !c.Open(got) {
+				t.Errorf("%s no está dentro del horario", got.In(c.Location))
+			}
+		})
+	}
+}
+
+// datesFrom devuelve n fechas YYYY-MM-DD consecutivas desde start
+func datesFrom(start time.Time, n int) []string {
+	dates := make([]string, n)
+	for i := range dates {
+		dates[i] = start.AddDate(0, 0, i).Format("2006-01-02")
+	}
+	return dates
+}
@@ -0,0 +1,221 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spec decide cuándo corre la siguiente sincronización
+type Spec interface {
+	Next(after time.Time) time.Time
+	String() string
+}
+
+// ParseSpec interpreta SYNC_INTERVAL_MINUTES: un número de minutos, o una o más
+// expresiones cron de 5 campos separadas por ';' (corre cuando coincide
+// cualquiera). Las expresiones cron se evalúan en la zona horaria loc.
+//
+//	*/5 8-18 * * mon-fri; 0 * * * *   cada 5 minutos en horario laboral y cada hora el resto
+func ParseSpec(value string, loc *time.Location) (Spec, error) {
+	value = strings.TrimSpace(value)
+	if minutes, err := strconv.Atoi(value); err == nil {
+		if minutes < 1 {
+			return nil, fmt.Errorf("el intervalo debe ser de al menos 1 minuto (valor: %d)", minutes)
+		}
+		return every(time.Duration(minutes) * time.Minute), nil
+	}
+
+	var spec cronSpec
+	for _, expr := range strings.Split(value, ";") {
+		if strings.TrimSpace(expr) == "" {
+			continue
+		}
+		c, err := parseCron(expr, loc)
+		if err != nil {
+			return nil, err
+		}
+		spec = append(spec, c)
+	}
+	if len(spec) == 0 {
+		return nil, fmt.Errorf("%q no es un número de minutos ni una expresión cron", value)
+	}
+	return spec, nil
+}
+
+// every es un intervalo fijo
+type every time.Duration
+
+func (e every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+func (e every) String() string {
+	return fmt.Sprintf("cada %d minutos", int(time.Duration(e)/time.Minute))
+}
+
+// cronSpec es la unión de varias expresiones cron
+type cronSpec []*cron
+
+func (s cronSpec) Next(after time.Time) time.Time {
+	var next time.Time
+	for _, c := range s {
+		if t := c.next(after); next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+	return next
+}
+
+func (s cronSpec) String() string {
+	exprs := make([]string, len(s))
+	for i, c := range s {
+		exprs[i] = c.expr
+	}
+	return "cron " + strings.Join(exprs, "; ")
+}
+
+// cron es una expresión de 5 campos: minuto, hora, día del mes, mes y día de
+// la semana. Cada campo admite '*', valores, rangos (a-b), listas (a,b) y
+// pasos (*/n, a-b/n). El día de la semana va de 0 (domingo) a 7 (también
+// domingo) o en inglés abreviado. Como en cron, si se restringen el día del
+// mes y el de la semana, basta con que coincida uno de los dos.
+type cron struct {
+	expr                         string
+	minute, hour, dom, month     uint64 // bits de los valores permitidos
+	dow                          uint64
+	domRestricted, dowRestricted bool
+	loc                          *time.Location
+}
+
+var dowNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+func parseCron(expr string, loc *time.Location) (*cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expresión cron %q: se esperan 5 campos (minuto hora día mes día-semana)", strings.TrimSpace(expr))
+	}
+	c := &cron{expr: strings.Join(fields, " "), loc: loc}
+
+	var err error
+	if c.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("expresión cron %q, minuto: %v", c.expr, err)
+	}
+	if c.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("expresión cron %q, hora: %v", c.expr, err)
+	}
+	if c.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("expresión cron %q, día del mes: %v", c.expr, err)
+	}
+	if c.month, err = parseField(fields[3], 1, 12, nil); err != nil {
+		return nil, fmt.Errorf("expresión cron %q, mes: %v", c.expr, err)
+	}
+	if c.dow, err = parseField(strings.ToLower(fields[4]), 0, 7, dowNames); err != nil {
+		return nil, fmt.Errorf("expresión cron %q, día de la semana: %v", c.expr, err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1 // 7 también es domingo
+	}
+	c.domRestricted = fields[2] != "*"
+	c.dowRestricted = fields[4] != "*"
+	return c, nil
+}
+
+// parseField devuelve los valores permitidos de un campo como bits
+func parseField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangeText, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("paso %q inválido", stepText)
+			}
+			step = n
+		}
+
+		from, to := min, max
+		if rangeText != "*" {
+			fromText, toText, isRange := strings.Cut(rangeText, "-")
+			var err error
+			if from, err = parseValue(fromText, min, max, names); err != nil {
+				return 0, err
+			}
+			to = from
+			if isRange {
+				if to, err = parseValue(toText, min, max, names); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				to = max // "a/n" = desde a hasta el máximo
+			}
+			if to < from {
+				return 0, fmt.Errorf("rango %q invertido", rangeText)
+			}
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(text string, min, max int, names map[string]int) (int, error) {
+	if v, ok := names[text]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(text)
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("valor %q fuera de rango (%d-%d)", text, min, max)
+	}
+	return v, nil
+}
+
+// next devuelve el primer minuto posterior a after que coincide con la
+// expresión. Busca hasta cinco años adelante (p. ej. "0 0 29 2 *"). Una hora
+// que no existe por el cambio al horario de verano no coincide.
+func (c *cron) next(after time.Time) time.Time {
+	t := after.In(c.loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = forward(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.loc))
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.loc))
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.loc))
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return limit
+}
+
+// forward avanza a next, o un minuto si next no queda después de t: cuando la
+// hora pedida cae en el salto del horario de verano, time.Date puede devolver
+// una hora anterior y la búsqueda no avanzaría nunca
+func forward(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Minute)
+}
+
+func (c *cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domRestricted && c.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
@@ -0,0 +1,128 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// bogota no tiene horario de verano; newYork sí, para los casos de DST
+var (
+	bogota  = mustLocation("America/Bogota")
+	newYork = mustLocation("America/New_York")
+)
+
+func mustLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// TestParseSpecNext calcula la siguiente ejecución de cada expresión
+func TestParseSpecNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  string
+		loc   *time.Location
+		after time.Time
+		want  time.Time
+	}{
+		{"minutos", "15", bogota,
+			time.Date(2024, 6, 3, 10, 7, 30, 0, bogota), time.Date(2024, 6, 3, 10, 22, 30, 0, bogota)},
+		{"paso de minutos", "*/15 * * * *", bogota,
+			time.Date(2024, 6, 3, 10, 7, 30, 0, bogota), time.Date(2024, 6, 3, 10, 15, 0, 0, bogota)},
+		{"siguiente minuto exacto", "* * * * *", bogota,
+			time.Date(2024, 6, 3, 10, 7, 0, 0, bogota), time.Date(2024, 6, 3, 10, 8, 0, 0, bogota)},
+		{"rango con paso", "0 8-18/4 * * *", bogota,
+			time.Date(2024, 6, 3, 12, 0, 0, 0, bogota), time.Date(2024, 6, 3, 16, 0, 0, 0, bogota)},
+		{"valor con paso hasta el máximo", "0 20/2 * * *", bogota,
+			time.Date(2024, 6, 3, 21, 0, 0, 0, bogota), time.Date(2024, 6, 3, 22, 0, 0, 0, bogota)},
+		{"lista", "0 9,13 * * *", bogota,
+			time.Date(2024, 6, 3, 9, 30, 0, 0, bogota), time.Date(2024, 6, 3, 13, 0, 0, 0, bogota)},
+		{"rango de nombres", "0 9 * * mon-fri", bogota,
+			time.Date(2024, 6, 7, 10, 0, 0, 0, bogota), time.Date(2024, 6, 10, 9, 0, 0, 0, bogota)}, // viernes → lunes
+		{"nombres en mayúsculas", "0 9 * * SAT,SUN", bogota,
+			time.Date(2024, 6, 3, 10, 0, 0, 0, bogota), time.Date(2024, 6, 8, 9, 0, 0, 0, bogota)},
+		{"7 es domingo", "0 12 * * 7", bogota,
+			time.Date(2024, 6, 3, 10, 0, 0, 0, bogota), time.Date(2024, 6, 9, 12, 0, 0, 0, bogota)},
+		{"0 es domingo", "0 12 * * 0", bogota,
+			time.Date(2024, 6, 3, 10, 0, 0, 0, bogota), time.Date(2024, 6, 9, 12, 0, 0, 0, bogota)},
+		{"solo día de la semana", "0 9 * * mon", bogota,
+			time.Date(2024, 7, 30, 10, 0, 0, 0, bogota), time.Date(2024, 8, 5, 9, 0, 0, 0, bogota)},
+		{"día del mes o de la semana: coincide el mes", "0 9 1 * mon", bogota,
+			time.Date(2024, 7, 30, 10, 0, 0, 0, bogota), time.Date(2024, 8, 1, 9, 0, 0, 0, bogota)}, // jueves 1
+		{"día del mes o de la semana: coincide la semana", "0 9 1 * mon", bogota,
+			time.Date(2024, 8, 1, 10, 0, 0, 0, bogota), time.Date(2024, 8, 5, 9, 0, 0, 0, bogota)},
+		{"mes", "0 0 1 jan *", bogota, // los meses no admiten nombres
+			time.Time{}, time.Time{}},
+		{"29 de febrero", "0 0 29 2 *", bogota,
+			time.Date(2025, 3, 1, 0, 0, 0, 0, bogota), time.Date(2028, 2, 29, 0, 0, 0, 0, bogota)},
+		{"nunca coincide: límite de cinco años", "0 0 31 2 *", bogota,
+			time.Date(2024, 6, 3, 10, 0, 0, 0, bogota), time.Date(2029, 6, 3, 10, 1, 0, 0, bogota)},
+		{"la más próxima de varias", "0 18 * * *; 30 9 * * *", bogota,
+			time.Date(2024, 6, 3, 10, 0, 0, 0, bogota), time.Date(2024, 6, 3, 18, 0, 0, 0, bogota)},
+		{"en la zona del spec", "0 9 * * *", bogota,
+			time.Date(2024, 6, 3, 13, 0, 0, 0, time.UTC), time.Date(2024, 6, 3, 9, 0, 0, 0, bogota)}, // 08:00 en Bogotá
+		// El 2024-03-10 de 02:00 a 03:00 no existe en Nueva York: ese día no hay 02:30,
+		// y la búsqueda no debe quedarse en la hora anterior al salto
+		{"hora inexistente por DST", "30 2 * * *", newYork,
+			time.Date(2024, 3, 10, 0, 0, 0, 0, newYork), time.Date(2024, 3, 11, 2, 30, 0, 0, newYork)},
+		{"hora siguiente al salto de DST", "0 3 * * *", newYork,
+			time.Date(2024, 3, 10, 0, 0, 0, 0, newYork), time.Date(2024, 3, 10, 3, 0, 0, 0, newYork)},
+		{"día del cambio de DST", "0 9 * * *", newYork,
+			time.Date(2024, 3, 10, 0, 0, 0, 0, newYork), time.Date(2024, 3, 10, 9, 0, 0, 0, newYork)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := ParseSpec(tt.spec, tt.loc)
+			if tt.want.IsZero() {
+				if err == nil {
+					t.Fatalf("se esperaba un error para %q", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := spec.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("%s después de %s: %s, se esperaba %s", tt.spec, tt.after, got.In(tt.loc), tt.want)
+			}
+		})
+	}
+}
+
+// TestParseSpecErrors rechaza las expresiones inválidas con un mensaje que
+// indica el campo
+func TestParseSpecErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"0", "al menos 1 minuto"},
+		{"", "no es un número de minutos"},
+		{" ; ", "no es un número de minutos"},
+		{"* * * *", "5 campos"},
+		{"60 * * * *", "minuto"},
+		{"0 24 * * *", "hora"},
+		{"0 0 0 * *", "día del mes"},
+		{"0 0 * 13 *", "mes"},
+		{"0 0 * * 8", "día de la semana"},
+		{"0 0 * * fri-mon", "invertido"},
+		{"*/0 * * * *", "paso"},
+		{"0 18-9 * * *", "invertido"},
+		{"0 9 * * *; 0 25 * * *", "hora"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := ParseSpec(tt.spec, bogota)
+			if err == nil {
+				t.Fatalf("se esperaba un error para %q", tt.spec)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q no menciona %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -27,15 +28,13 @@ func New(basePath string) (*Storage, error) {
 	}, nil
 }
 
-// SaveIncident guarda una incidencia en archivo individual dentro de carpeta de assignee
+// SaveIncident guarda una incidencia en archivo individual dentro de carpeta de assignee.
+// La carpeta se nombra con el accountId para que un cambio de nombre en Jira no la duplique.
+// Si el archivo ya existe se reescribe solo cuando el contenido cambió (por ejemplo
+// componentes o etiquetas nuevos), para no tocar la fecha de los que siguen igual.
 func (s *Storage) SaveIncident(incident *jira.Incident) error {
-	// Solo guardar si no existe ya
-	if s.IncidentExists(incident.Key, incident.Assignee) {
-		return nil // No hacer nada si ya existe
-	}
-
 	// Crear carpeta para el assignee si no existe
-	assigneePath := s.getAssigneePath(incident.Assignee)
+	assigneePath := s.getAssigneePath(incident.AssigneeID)
 	if err := os.MkdirAll(assigneePath, 0755); err != nil {
 		return fmt.Errorf("error creando carpeta de assignee: %v", err)
 	}
@@ -48,22 +47,131 @@ func (s *Storage) SaveIncident(incident *jira.Incident) error {
 		return fmt.Errorf("error serializando incidencia: %v", err)
 	}
 
+	if current, err := os.ReadFile(filePath); err == nil && bytes.Equal(current, data) {
+		return nil
+	}
 	return os.WriteFile(filePath, data, 0644)
 }
 
-// IncidentExists verifica si ya existe un archivo para la incidencia en la carpeta del assignee
-func (s *Storage) IncidentExists(key, assignee string) bool {
+// MoveIncident mueve el archivo de una incidencia reasignada desde la carpeta del
+// assignee anterior a la del actual y lo actualiza con los datos vigentes. Si no
+// había archivo en la carpeta anterior no hace nada.
+func (s *Storage) MoveIncident(incident *jira.Incident, fromAssigneeID string) error {
+	fileName := s.getFileName(incident.Key)
+	fromPath := filepath.Join(s.getAssigneePath(fromAssigneeID), fileName)
+	if _, err := os.Stat(fromPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	assigneePath := s.getAssigneePath(incident.AssigneeID)
+	if err := os.MkdirAll(assigneePath, 0755); err != nil {
+		return fmt.Errorf("error creando carpeta de assignee: %v", err)
+	}
+	toPath := filepath.Join(assigneePath, fileName)
+	if err := os.Rename(fromPath, toPath); err != nil {
+		return fmt.Errorf("error moviendo incidencia %s: %v", incident.Key, err)
+	}
+
+	data, err := json.MarshalIndent(incident, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error serializando incidencia: %v", err)
+	}
+	return os.WriteFile(toPath, data, 0644)
+}
+
+// assigneeIDsMarker marca en el directorio base que MigrateAssigneeFolders ya se ejecutó
+const assigneeIDsMarker = ".assignee-ids"
+
+// MigrateAssigneeFolders mueve los archivos guardados cuando las carpetas se
+// nombraban con el nombre visible del assignee (sin assignee_id en el archivo)
+// a la carpeta de su accountId, y borra las carpetas que quedan vacías. resolve
+// traduce un nombre visible a accountId y siempre devuelve un identificador.
+// Se ejecuta una sola vez: al terminar deja una marca en el directorio base.
+// Devuelve la cantidad de archivos movidos.
+func (s *Storage) MigrateAssigneeFolders(resolve func(displayName string) string) (int, error) {
+	marker := filepath.Join(s.basePath, assigneeIDsMarker)
+	if _, err := os.Stat(marker); err == nil {
+		return 0, nil
+	}
+
+	dirs, err := os.ReadDir(s.basePath)
+	if err != nil {
+		return 0, fmt.Errorf("error leyendo %s: %v", s.basePath, err)
+	}
+	resolved := make(map[string]string)
+	moved := 0
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		dirPath := filepath.Join(s.basePath, dir.Name())
+		files, err := os.ReadDir(dirPath)
+		if err != nil {
+			return moved, fmt.Errorf("error leyendo %s: %v", dirPath, err)
+		}
+		for _, file := range files {
+			if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+				continue
+			}
+			fromPath := filepath.Join(dirPath, file.Name())
+			data, err := os.ReadFile(fromPath)
+			if err != nil {
+				return moved, err
+			}
+			var incident jira.Incident
+			if err := json.Unmarshal(data, &incident); err != nil {
+				return moved, fmt.Errorf("error leyendo %s: %v", fromPath, err)
+			}
+			if incident.AssigneeID != "" || incident.Assignee == "" {
+				continue
+			}
+
+			accountID, ok := resolved[incident.Assignee]
+			if !ok {
+				accountID = resolve(incident.Assignee)
+				resolved[incident.Assignee] = accountID
+			}
+			incident.AssigneeID = accountID
+
+			assigneePath := s.getAssigneePath(accountID)
+			if err := os.MkdirAll(assigneePath, 0755); err != nil {
+				return moved, fmt.Errorf("error creando carpeta de assignee: %v", err)
+			}
+			data, err = json.MarshalIndent(&incident, "", "  ")
+			if err != nil {
+				return moved, fmt.Errorf("error serializando incidencia: %v", err)
+			}
+			toPath := filepath.Join(assigneePath, file.Name())
+			if err := os.WriteFile(toPath, data, 0644); err != nil {
+				return moved, err
+			}
+			if toPath != fromPath {
+				if err := os.Remove(fromPath); err != nil {
+					return moved, err
+				}
+			}
+			moved++
+		}
+		// Solo se borra si quedó vacía
+		os.Remove(dirPath)
+	}
+
+	return moved, os.WriteFile(marker, nil, 0644)
+}
+
+// IncidentExists verifica si ya existe un archivo para la incidencia en la carpeta del assignee (accountId)
+func (s *Storage) IncidentExists(key, assigneeID string) bool {
 	fileName := s.getFileName(key)
-	assigneePath := s.getAssigneePath(assignee)
+	assigneePath := s.getAssigneePath(assigneeID)
 	filePath := filepath.Join(assigneePath, fileName)
 	_, err := os.Stat(filePath)
 	return !os.IsNotExist(err)
 }
 
-// GetIncident carga una incidencia desde archivo en la carpeta del assignee
-func (s *Storage) GetIncident(key, assignee string) (*jira.Incident, error) {
+// GetIncident carga una incidencia desde archivo en la carpeta del assignee (accountId)
+func (s *Storage) GetIncident(key, assigneeID string) (*jira.Incident, error) {
 	fileName := s.getFileName(key)
-	assigneePath := s.getAssigneePath(assignee)
+	assigneePath := s.getAssigneePath(assigneeID)
 	filePath := filepath.Join(assigneePath, fileName)
 
 	data, err := os.ReadFile(filePath)
@@ -117,7 +225,7 @@ func (s *Storage) getFileName(key string) string {
 	return safeKey + ".json"
 }
 
-// getAssigneePath genera la ruta de carpeta para un assignee
+// getAssigneePath genera la ruta de carpeta para un assignee (accountId)
 func (s *Storage) getAssigneePath(assignee string) string {
 	if assignee == "" {
 		assignee = "Unassigned"
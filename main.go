@@ -1,11 +1,15 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 	"unsafe"
@@ -15,6 +19,13 @@ import (
 	"github.com/PhelGc/furina-sync/internal/discord"
 	"github.com/PhelGc/furina-sync/internal/evaluator"
 	"github.com/PhelGc/furina-sync/internal/jira"
+	"github.com/PhelGc/furina-sync/internal/leader"
+	"github.com/PhelGc/furina-sync/internal/metrics"
+	"github.com/PhelGc/furina-sync/internal/outbox"
+	"github.com/PhelGc/furina-sync/internal/reconcile"
+	"github.com/PhelGc/furina-sync/internal/reload"
+	"github.com/PhelGc/furina-sync/internal/reminder"
+	"github.com/PhelGc/furina-sync/internal/report"
 	"github.com/PhelGc/furina-sync/internal/storage"
 )
 
@@ -41,29 +52,33 @@ const (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
 	log.Println("Furina Sync iniciando...")
 
+	// Load valida campos obligatorios, formatos y rangos, y reporta todo junto
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Error cargando configuración: %v", err)
-	}
-
-	// Validar configuración del evaluador
-	if !cfg.Eval.Enabled {
-		log.Fatalf("EVAL_ENABLED debe estar en true. El evaluador IA es requerido.")
+		log.Fatalf("Configuración inválida:\n%v", err)
 	}
-	if cfg.Eval.APIKey == "" {
-		log.Fatalf("GEMINI_API_KEY no está configurado en el .env")
+	// config guarda los horarios como texto; se interpretan aquí
+	sched, err := loadSchedules(cfg)
+	if err != nil {
+		log.Fatalf("Configuración inválida:\n%v", err)
 	}
 
 	// Cargar prompts desde archivos externos (falla explícitamente si no existen)
-	prompts, err := evaluator.LoadPrompts(cfg.Eval.PromptPhase1, cfg.Eval.PromptPhase2)
+	prompts, err := evaluator.LoadPrompts(cfg.Eval)
 	if err != nil {
 		log.Fatalf("Error cargando prompts: %v", err)
 	}
-	log.Printf("Prompts cargados: %s, %s", cfg.Eval.PromptPhase1, cfg.Eval.PromptPhase2)
+	for _, set := range prompts.Sets {
+		log.Printf("Set de prompts cargado: %s (umbral %d)", set.Version(), set.Threshold)
+	}
 
-	evalClient := evaluator.NewClient(cfg.Eval.APIKey, cfg.Eval.Model, prompts)
+	evalClient := evaluator.NewClient(cfg.Eval, prompts)
 
 	store, err := storage.New(cfg.Storage.BasePath)
 	if err != nil {
@@ -76,56 +91,192 @@ func main() {
 	}
 
 	discordClient, err := discord.NewClient(&discord.Config{
-		BotToken:    cfg.Discord.BotToken,
-		GuildID:     cfg.Discord.GuildID,
-		Channels:    cfg.Discord.Channels,
-		JiraBaseURL: cfg.Jira.URL,
+		BotToken:        cfg.Discord.BotToken,
+		GuildID:         cfg.Discord.GuildID,
+		Channels:        cfg.Discord.Channels,
+		RoutesFile:      cfg.Discord.RoutesFile,
+		JiraBaseURL:     cfg.Jira.URL,
+		FallbackChannel: cfg.Discord.FallbackChannel,
+		Users:           cfg.Discord.Users,
+		UnmappedPolicy:  cfg.Discord.UnmappedPolicy,
 	})
 	if err != nil {
 		log.Fatalf("Error creando cliente Discord: %v", err)
 	}
 	defer discordClient.Close()
+	if router := discordClient.Router(); router.File != "" {
+		log.Printf("Reglas de ruteo cargadas desde %s: %d (modo %s)", router.File, len(router.Rules), router.Mode)
+	}
 
-	dbClient, err := database.NewClient(&database.Config{
-		Host:     cfg.Database.Host,
-		Port:     cfg.Database.Port,
-		Username: cfg.Database.Username,
-		Password: cfg.Database.Password,
-		Database: cfg.Database.Database,
-	})
+	// Registros anteriores a accountId (migración 0007): se resuelven con las
+	// incidencias actuales y, si el assignee ya no tiene ninguna, con la
+	// búsqueda de usuarios de Jira
+	resolve := assigneeResolver(jiraClient)
+	dbClient, err := openDatabase(cfg, resolve)
 	if err != nil {
 		log.Fatalf("Error conectando a la base de datos: %v", err)
 	}
 	defer dbClient.Close()
 
-	if err := dbClient.CreateTable(); err != nil {
-		log.Fatalf("Error creando tabla discord_messages: %v", err)
+	if err := dbClient.Migrate(); err != nil {
+		log.Fatalf("Error aplicando migraciones: %v", err)
+	}
+
+	// Las carpetas de storage se migran con el mismo resolver; los nombres sin
+	// accountId quedan como en la BD, y se re-asocian al sincronizar la incidencia
+	moved, err := store.MigrateAssigneeFolders(func(displayName string) string {
+		accountID, err := resolve(displayName)
+		if err != nil || accountID == "" {
+			log.Printf(clrYellow+"Advertencia: no se pudo resolver accountId de %q: %v"+clrReset, displayName, err)
+			return database.LegacyAssigneeID(displayName)
+		}
+		return accountID
+	})
+	if err != nil {
+		log.Fatalf("Error migrando carpetas de storage a accountId: %v", err)
+	}
+	if moved > 0 {
+		log.Printf("Incidencias de storage migradas a carpetas por accountId: %d", moved)
+	}
+
+	// Las operaciones de Discord pasan por el outbox: lo que quedó pendiente de
+	// una ejecución anterior se aplica al empezar la primera sincronización.
+	// Fuera del horario laboral se acumulan hasta que empieza.
+	if sched.calendar != nil {
+		log.Printf("Horario laboral: %s %s (%s)", cfg.Schedule.WorkDays, cfg.Schedule.WorkHours, sched.calendar.Location)
+	}
+
+	// Con varias instancias (p. ej. durante un deploy) solo sincroniza la que
+	// tiene el lease; las demás esperan a que venza para tomarlo. Ctrl+C o
+	// SIGTERM cancelan ctx: la sincronización en curso se corta y después se
+	// libera el lease.
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	var elector *leader.Elector
+	var lease database.Lease
+	if cfg.Leader.LeaseSeconds > 0 {
+		elector = leader.New(dbClient, "sync", cfg.Leader.ID, time.Duration(cfg.Leader.LeaseSeconds)*time.Second)
+		elector.Start(ctx)
+		lease = database.Lease{Name: "sync", Holder: elector.ID()}
+	}
+	// leaderContext se cancela si la instancia deja de ser líder a mitad de una
+	// sincronización; si ya no lo es viene cancelado
+	leaderContext := func() context.Context {
+		if elector == nil {
+			return ctx
+		}
+		return elector.Context()
+	}
+
+	dispatcher := outbox.New(dbClient, discordClient, sched.calendar, lease)
+	reconciler := reconcile.New(dbClient, discordClient, lease)
+
+	// Resúmenes periódicos de evaluaciones por canal y por equipo
+	reporter := report.New(dbClient, discordClient, dispatcher, lease, sched.location, sched.digests)
+	for _, digest := range sched.digests {
+		log.Printf("Resumen %s en %s: %s", digest.Name, digest.Channel, digest.Spec)
 	}
-	if err := dbClient.CreateEvaluationTable(); err != nil {
-		log.Fatalf("Error creando tabla incident_evaluations: %v", err)
+
+	if cfg.Metrics.Addr != "" {
+		metrics.Serve(cfg.Metrics.Addr)
 	}
 
-	ticker := time.NewTicker(time.Duration(cfg.Sync.IntervalMinutes) * time.Minute)
-	defer ticker.Stop()
+	// Recarga en caliente de prompts y canales (cambios en archivos o SIGHUP)
+	reloader := reload.New(evalClient, discordClient)
+	go reloader.Run(time.Duration(cfg.Sync.WatchIntervalSeconds) * time.Second)
 
-	log.Printf("Sincronización cada %d minutos · Modelo: %s", cfg.Sync.IntervalMinutes, cfg.Eval.Model)
+	spec := sched.sync
+	log.Printf("Sincronización %s · Modelo: %s", spec, cfg.Eval.Model)
 
-	syncIncidents(jiraClient, store, discordClient, dbClient, evalClient)
+	reminders := sched.reminders
 
-	for range ticker.C {
-		syncIncidents(jiraClient, store, discordClient, dbClient, evalClient)
+	// La reconciliación y los resúmenes corren después de una sincronización,
+	// nunca a la vez. Los resúmenes además se revisan a su hora si cae entre
+	// dos sincronizaciones (ver el bucle de abajo).
+	reconcileEvery := time.Duration(cfg.Sync.ReconcileIntervalMinutes) * time.Minute
+	var lastReconcile time.Time
+	runSync := func() {
+		syncCtx := leaderContext()
+		if syncCtx.Err() != nil {
+			return
+		}
+		syncIncidents(syncCtx, jiraClient, store, discordClient, dbClient, dispatcher, reminders, evalClient, cfg.Eval, cfg.Discord.HandoverNotice)
+		if syncCtx.Err() != nil {
+			return
+		}
+		if reconcileEvery > 0 && time.Since(lastReconcile) >= reconcileEvery {
+			reconciler.Run(syncCtx)
+			lastReconcile = time.Now()
+		}
+		reporter.RunDue(syncCtx, time.Now())
 	}
+
+	runSync()
+
+	// Como un ticker: si una sincronización se alarga, las ejecuciones que se
+	// perdieron no se recuperan. Si un resumen toca antes de la siguiente
+	// sincronización se despierta a su hora solo para publicarlo.
+	next := spec.Next(time.Now())
+	for {
+		wake := next
+		if due := reporter.NextDue(); due.After(time.Now()) && due.Before(wake) {
+			wake = due
+		}
+		timer := time.NewTimer(time.Until(wake))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			shutdown(elector, dbClient)
+			return
+		case <-timer.C:
+		}
+		if next.After(time.Now()) {
+			if leaderCtx := leaderContext(); leaderCtx.Err() == nil {
+				reporter.RunDue(leaderCtx, time.Now())
+			}
+			continue
+		}
+		for !next.After(time.Now()) {
+			next = spec.Next(next)
+		}
+		runSync()
+	}
+}
+
+// shutdown se llama cuando ya no hay una sincronización en curso: libera el
+// lease para que otra instancia no espere a que venza y cierra la base de datos
+func shutdown(elector *leader.Elector, dbClient database.Repository) {
+	log.Println("Deteniendo Furina Sync...")
+	if elector != nil {
+		elector.Stop()
+	}
+	dbClient.Close()
 }
 
+// syncIncidents sincroniza una vez. Si ctx se cancela (la instancia dejó de ser
+// líder o se está deteniendo) no empieza ninguna incidencia más ni aplica más
+// operaciones de Discord: lo que falte lo retoma la siguiente sincronización.
 func syncIncidents(
+	ctx context.Context,
 	jiraClient *jira.Client,
 	store *storage.Storage,
 	discordClient *discord.Client,
-	dbClient *database.Client,
+	dbClient database.Repository,
+	dispatcher *outbox.Dispatcher,
+	reminders *reminder.Policy,
 	evalClient *evaluator.Client,
+	evalCfg config.EvalConfig,
+	handoverNotice bool,
 ) {
 	log.Println("Sincronizando incidencias de Jira...")
 
+	// Antes de leer los mensajes de la BD: completar operaciones cambia discord_messages
+	dispatcher.Replay(ctx)
+	if ctx.Err() != nil {
+		log.Printf(clrYellow + "Sincronización interrumpida antes de empezar: esta instancia dejó de ser líder o se está deteniendo" + clrReset)
+		return
+	}
+
 	incidents, err := jiraClient.GetIncidents()
 	if err != nil {
 		log.Printf(clrRed+"Error obteniendo incidencias: %v"+clrReset, err)
@@ -141,7 +292,7 @@ func syncIncidents(
 	messageCache, err := dbClient.GetMessagesByKeys(currentKeys)
 	if err != nil {
 		log.Printf(clrYellow+"Advertencia: error cargando caché de mensajes: %v"+clrReset, err)
-		messageCache = make(map[string]*database.MessageToDelete)
+		messageCache = make(map[string][]*database.MessageToDelete)
 	}
 
 	evalCache, err := dbClient.GetEvaluationsByKeys(currentKeys)
@@ -151,12 +302,19 @@ func syncIncidents(
 	}
 
 	type result struct {
-		isNew    bool
-		evaluated bool
-		skipped  bool
-		hasError bool
+		isNew       bool
+		evaluated   bool
+		skipped     bool
+		reassigned  bool
+		undelivered bool
+		deferred    bool
+		hasError    bool
+		canceled    bool
 	}
 
+	// Cupo de re-evaluaciones por cambio de prompt en este ciclo (política gradual)
+	reevalBudget := int64(evalCfg.ReevalMaxPerTick)
+
 	jobs := make(chan *jira.Incident, len(incidents))
 	results := make(chan result, len(incidents))
 
@@ -167,13 +325,116 @@ func syncIncidents(
 			defer wg.Done()
 			for incident := range jobs {
 				r := result{}
+				if ctx.Err() != nil {
+					r.canceled = true
+					results <- r
+					continue
+				}
+
+				// Con operaciones pendientes de un ciclo anterior no se planifican otras:
+				// las duplicarían. Si Discord sigue fallando se espera al siguiente
+				// ciclo; si esperan el horario laboral la incidencia se puede re-evaluar
+				// igual (la evaluación nueva reemplaza los envíos pendientes).
+				pending := dispatcher.Pending(incident.Key)
+				if pending && dispatcher.Open() {
+					log.Printf(clrYellow+"Advertencia: %s tiene operaciones de Discord pendientes, se reintenta en el siguiente ciclo"+clrReset, incident.Key)
+					r.hasError = true
+					results <- r
+					continue
+				}
+				r.deferred = pending
 
 				cachedEval := evalCache[incident.Key]
-				existingMsg := messageCache[incident.Key+":"+incident.Assignee]
 
-				// Comparar con segundo de precisión (MySQL DATETIME no guarda milisegundos)
-				needsEval := cachedEval == nil ||
-					cachedEval.JiraUpdatedAt.Unix() != incident.UpdatedDate.Unix()
+				// Mensajes de la incidencia: los enviados con el assignee actual y los
+				// que quedaron de assignees anteriores
+				var existing, previous []*database.MessageToDelete
+				for _, msg := range messageCache[incident.Key] {
+					if msg.AssigneeID == incident.AssigneeID {
+						existing = append(existing, msg)
+					} else {
+						previous = append(previous, msg)
+					}
+				}
+				if len(previous) > 0 {
+					// Un "name:<nombre>" con el nombre del assignee actual es la misma persona
+					var rekeyed []*database.MessageToDelete
+					rekeyed, previous = rekeyLegacyAssignee(incident, previous, store, dbClient)
+					existing = append(existing, rekeyed...)
+				}
+				// Los mensajes de assignees anteriores se borran en la misma entrega que
+				// publica la evaluación, después de los envíos
+				var replaced []*database.MessageToDelete
+				if len(previous) > 0 && !pending {
+					r.reassigned = true
+					replaced = previous
+					handleReassignment(incident, previous, store, discordClient, dbClient, handoverNotice)
+				}
+
+				// Si los comentarios cuentan para el hash hay que descargarlos antes de compararlo
+				commentsLoaded := false
+				if evalCfg.HashComments {
+					loadComments(jiraClient, incident)
+					commentsLoaded = true
+				}
+
+				// Re-evaluar solo si cambiaron las entradas evaluadas; mover la incidencia
+				// de sprint o agregar etiquetas actualiza UpdatedDate pero no el hash.
+				// Las filas anteriores al versionado de prompts no tienen versión y su
+				// hash usaba otras entradas: el contenido se compara como antes, por la
+				// fecha de Jira (con segundo de precisión, MySQL DATETIME no guarda
+				// milisegundos), y la versión vacía queda sujeta a la política.
+				needsEval := cachedEval == nil
+				switch {
+				case needsEval:
+				case cachedEval.PromptVersion == "":
+					needsEval = cachedEval.JiraUpdatedAt.Unix() != incident.UpdatedDate.Unix()
+				default:
+					needsEval = cachedEval.InputHash != evalClient.InputHash(incident, cachedEval.PromptVersion)
+				}
+
+				// Mismo contenido pero evaluado con otra versión o set de prompts: aplicar política
+				promptVersion := evalClient.PromptVersion(incident)
+				if !needsEval && cachedEval.PromptVersion != promptVersion {
+					switch evalCfg.ReevalPolicy {
+					case config.ReevalAll:
+						needsEval = true
+					case config.ReevalGradual:
+						needsEval = atomic.AddInt64(&reevalBudget, -1) >= 0
+					}
+					if needsEval {
+						log.Printf(clrCyan+"Prompt actualizado, re-evaluando %s (%s → %s)"+clrReset,
+							incident.Key, cachedEval.PromptVersion, promptVersion)
+					}
+				}
+
+				// Evaluación vigente: publicarla desde la caché, sin volver a llamar al
+				// modelo, en los destinos que no tengan mensaje
+				if !needsEval && !pending {
+					eval, err := evalClient.Restore(incident, cachedEval.PromptVersion, cachedEval.PhaseResults)
+					if err != nil {
+						// Filas anteriores a phase_results: solo importa si no hay nada publicado
+						if len(existing) == 0 {
+							log.Printf(clrYellow+"Advertencia: no se pudo recuperar la evaluación en caché de %s, se re-evalúa: %v"+clrReset, incident.Key, err)
+							needsEval = true
+						} else if err := deleteReplaced(ctx, incident, replaced, dbClient, dispatcher); err != nil {
+							log.Printf(clrYellow+"Advertencia: no se pudieron borrar mensajes anteriores de %s: %v"+clrReset, incident.Key, err)
+						}
+					} else {
+						sent, err := deliverFromCache(ctx, incident, eval, existing, replaced, reminders, discordClient, dbClient, dispatcher)
+						switch {
+						case errors.Is(err, discord.ErrNoDestination):
+							r.undelivered = true
+						case errors.Is(err, outbox.ErrDeferred):
+							r.deferred = true
+						case err != nil:
+							log.Printf(clrRed+"Error enviando evaluación %s desde caché: %v"+clrReset, incident.Key, err)
+							r.hasError = true
+						case sent > 0:
+							log.Printf(clrGreen+"Evaluación publicada desde caché: %s (%d canal(es))"+clrReset, incident.Key, sent)
+						}
+					}
+				}
 
 				if !needsEval {
 					r.skipped = true
@@ -181,12 +442,17 @@ func syncIncidents(
 					continue
 				}
 
+				if !commentsLoaded {
+					loadComments(jiraClient, incident)
+				}
+
 				// Detectar si es nueva (primera vez que la vemos)
-				r.isNew = !store.IncidentExists(incident.Key, incident.Assignee)
+				r.isNew = !store.IncidentExists(incident.Key, incident.AssigneeID)
+				// Se guarda también si ya existía: componentes y etiquetas forman parte del hash
+				if err := store.SaveIncident(incident); err != nil {
+					log.Printf(clrRed+"Error guardando incidencia %s: %v"+clrReset, incident.Key, err)
+				}
 				if r.isNew {
-					if err := store.SaveIncident(incident); err != nil {
-						log.Printf(clrRed+"Error guardando incidencia %s: %v"+clrReset, incident.Key, err)
-					}
 					log.Printf(clrGreen+"Nueva incidencia: %s (Assignee: %s)"+clrReset, incident.Key, incident.Assignee)
 				} else {
 					log.Printf(clrCyan+"Incidencia actualizada: %s (Assignee: %s)"+clrReset, incident.Key, incident.Assignee)
@@ -201,46 +467,68 @@ func syncIncidents(
 					continue
 				}
 
-				// Borrar mensaje anterior de Discord si existe
-				if existingMsg != nil {
-					if err := discordClient.DeleteMessage(existingMsg.ChannelID, existingMsg.MessageID); err != nil {
-						log.Printf(clrYellow+"Advertencia: no se pudo borrar mensaje anterior %s: %v"+clrReset, existingMsg.MessageID, err)
-					}
+				// Quien tome el lease la evalúa de nuevo; guardarla ahora duplicaría la entrega
+				if ctx.Err() != nil {
+					r.canceled = true
+					results <- r
+					continue
 				}
 
-				// Enviar evaluación a Discord
-				discordInc := convertToDiscordIncident(incident)
-				messageID, err := discordClient.SendEvaluationResult(discordInc, eval)
-				if err != nil {
-					log.Printf(clrRed+"Error enviando evaluación %s: %v"+clrReset, incident.Key, err)
+				// La evaluación se guarda junto con las operaciones que la publican: si la
+				// entrega falla no se vuelve a llamar al modelo, y lo pendiente se
+				// reintenta desde el outbox
+				entries, planErr := planDelivery(incident, eval, existing, replaced, true, nil, discordClient)
+				phasesJSON, _ := eval.PhasesJSON()
+				// El hash usa la versión con que se evaluó realmente (los prompts pueden recargarse a mitad de ciclo)
+				inputHash := evalClient.InputHash(incident, eval.PromptVersion)
+				record := database.HistoryEntry{
+					IncidentKey:   incident.Key,
+					Title:         incident.Title,
+					AssigneeID:    incident.AssigneeID,
+					Assignee:      incident.Assignee,
+					PromptSet:     eval.PromptSet,
+					PromptVersion: eval.PromptVersion,
+					PhaseResults:  phasesJSON,
+				}
+				if err := dbClient.SaveEvaluation(record, incident.UpdatedDate, inputHash, entries); err != nil {
+					log.Printf(clrRed+"Error guardando evaluación para %s: %v"+clrReset, incident.Key, err)
 					r.hasError = true
 					results <- r
 					continue
 				}
+				r.evaluated = true
 
-				// Guardar mensaje en BD
-				channelID, _ := discordClient.GetChannelForAssignee(incident.Assignee)
-				if err := dbClient.UpsertMessage(incident.Key, channelID, messageID, incident.Assignee); err != nil {
-					log.Printf(clrYellow+"Advertencia: error guardando mensaje BD para %s: %v"+clrReset, incident.Key, err)
+				// Enviar evaluación a Discord
+				_, err = dispatcher.Dispatch(ctx, incident.Key)
+				if errors.Is(err, outbox.ErrDeferred) {
+					r.deferred = true
+					err = nil
 				}
-
-				// Guardar evaluación en caché BD
-				p1JSON, _ := json.Marshal(eval.Phase1)
-				var p2 interface{}
-				if eval.Phase2 != nil {
-					p2b, _ := json.Marshal(eval.Phase2)
-					p2 = string(p2b)
+				if err == nil {
+					err = planErr
 				}
-				if err := dbClient.UpsertEvaluation(incident.Key, incident.UpdatedDate, string(p1JSON), p2); err != nil {
-					log.Printf(clrYellow+"Advertencia: error guardando evaluación para %s: %v"+clrReset, incident.Key, err)
+				if err != nil {
+					if errors.Is(err, discord.ErrNoDestination) {
+						log.Printf(clrYellow+"Evaluación de %s guardada sin enviar: %v"+clrReset, incident.Key, err)
+						r.undelivered = true
+					} else {
+						log.Printf(clrRed+"Error enviando evaluación %s: %v"+clrReset, incident.Key, err)
+						r.hasError = true
+					}
+					results <- r
+					continue
 				}
 
-				scoreLog := fmt.Sprintf("D:%d/100", eval.Phase1.Puntaje)
-				if eval.Phase2 != nil {
-					scoreLog += fmt.Sprintf(" C:%d/100", eval.Phase2.Puntaje)
+				var scores []string
+				for _, phase := range eval.Scored() {
+					scores = append(scores, fmt.Sprintf("%s:%d/100", phase.Name, phase.Score))
+				}
+				scoreLog := strings.Join(scores, " ")
+				if r.deferred {
+					log.Printf(clrCyan+"Evaluación en espera del horario laboral: %s [%s]"+clrReset, incident.Key, scoreLog)
+				} else {
+					log.Printf(clrGreen+"Evaluación enviada: %s [%s]"+clrReset, incident.Key, scoreLog)
 				}
-				log.Printf(clrGreen+"Evaluación enviada: %s [%s]"+clrReset, incident.Key, scoreLog)
-				r.evaluated = true
 				results <- r
 			}
 		}()
@@ -256,11 +544,23 @@ func syncIncidents(
 		close(results)
 	}()
 
-	newCount, evaluatedCount, skippedCount, errorCount := 0, 0, 0, 0
+	newCount, evaluatedCount, skippedCount, reassignedCount, undeliveredCount, deferredCount, errorCount, canceledCount := 0, 0, 0, 0, 0, 0, 0, 0
 	for r := range results {
+		if r.canceled {
+			canceledCount++
+		}
 		if r.isNew {
 			newCount++
 		}
+		if r.reassigned {
+			reassignedCount++
+		}
+		if r.undelivered {
+			undeliveredCount++
+		}
+		if r.deferred {
+			deferredCount++
+		}
 		if r.evaluated {
 			evaluatedCount++
 		}
@@ -272,18 +572,377 @@ func syncIncidents(
 		}
 	}
 
+	if ctx.Err() != nil {
+		log.Printf(clrYellow+"Sincronización interrumpida: esta instancia dejó de ser líder o se está deteniendo (%d incidencia(s) sin procesar)"+clrReset, canceledCount)
+		return
+	}
+
 	// Limpiar mensajes de incidencias que ya no están en Jira
-	if err := dbClient.CleanupRemovedIncidents(currentKeys, discordClient); err != nil {
+	if err := cleanupRemoved(ctx, currentKeys, dbClient, dispatcher); err != nil {
 		log.Printf(clrRed+"Error en limpieza: %v"+clrReset, err)
 		errorCount++
 	}
 
 	if errorCount > 0 {
-		log.Printf(clrRed+"Sync con %d error(es). Nuevas: %d | Evaluadas: %d | Omitidas: %d"+clrReset,
-			errorCount, newCount, evaluatedCount, skippedCount)
+		log.Printf(clrRed+"Sync con %d error(es). Nuevas: %d | Evaluadas: %d | Omitidas: %d | Reasignadas: %d | Sin canal: %d | En espera: %d"+clrReset,
+			errorCount, newCount, evaluatedCount, skippedCount, reassignedCount, undeliveredCount, deferredCount)
 	} else {
-		log.Printf(clrGreen+"Sync OK — Nuevas: %d | Evaluadas: %d | Omitidas: %d"+clrReset,
-			newCount, evaluatedCount, skippedCount)
+		log.Printf(clrGreen+"Sync OK — Nuevas: %d | Evaluadas: %d | Omitidas: %d | Reasignadas: %d | Sin canal: %d | En espera: %d"+clrReset,
+			newCount, evaluatedCount, skippedCount, reassignedCount, undeliveredCount, deferredCount)
+	}
+}
+
+// handleReassignment mueve el archivo de storage de una incidencia reasignada a
+// la carpeta del nuevo assignee y, si está habilitado, registra en el outbox
+// los avisos para los canales de ambos: salen con la entrega de la incidencia,
+// con sus reintentos y dentro del horario laboral. Los mensajes del assignee
+// anterior no se borran aquí: van en la entrega de la evaluación (ver
+// planDelivery), después de los envíos. Los errores se registran pero no
+// detienen la sincronización.
+func handleReassignment(
+	incident *jira.Incident,
+	previous []*database.MessageToDelete,
+	store *storage.Storage,
+	discordClient *discord.Client,
+	dbClient database.Repository,
+	handoverNotice bool,
+) {
+	// Un assignee anterior puede tener mensajes en varios canales
+	channelsByAssignee := make(map[string][]string)
+	names := make(map[string]string)
+	for _, msg := range previous {
+		channelsByAssignee[msg.AssigneeID] = append(channelsByAssignee[msg.AssigneeID], msg.ChannelID)
+		names[msg.AssigneeID] = msg.Assignee
+	}
+
+	for assigneeID, channels := range channelsByAssignee {
+		log.Printf(clrCyan+"Incidencia reasignada: %s (%s → %s)"+clrReset, incident.Key, names[assigneeID], incident.Assignee)
+
+		if err := store.MoveIncident(incident, assigneeID); err != nil {
+			log.Printf(clrYellow+"Advertencia: %v"+clrReset, err)
+		}
+		if handoverNotice {
+			if err := enqueueHandover(incident, names[assigneeID], channels, discordClient, dbClient); err != nil {
+				log.Printf(clrYellow+"Advertencia: no se pudo registrar el aviso de reasignación de %s: %v"+clrReset, incident.Key, err)
+			}
+		}
+	}
+}
+
+// enqueueHandover registra en el outbox los avisos de una reasignación
+func enqueueHandover(incident *jira.Incident, fromName string, fromChannels []string, discordClient *discord.Client, dbClient database.Repository) error {
+	var entries []database.OutboxEntry
+	for _, notice := range discordClient.HandoverNotices(convertToDiscordIncident(incident), fromName, fromChannels) {
+		ref := database.NewOutboxRef()
+		payload, err := discordClient.NoticePayload(notice.Text, ref)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, database.OutboxEntry{
+			Ref:         ref,
+			IncidentKey: incident.Key,
+			Operation:   database.OutboxNotice,
+			ChannelID:   notice.ChannelID,
+			AssigneeID:  incident.AssigneeID,
+			Assignee:    incident.Assignee,
+			Payload:     payload,
+		})
+	}
+	return dbClient.EnqueueOutbox(entries)
+}
+
+// rekeyLegacyAssignee re-asocia al accountId actual los mensajes que la
+// migración a accountId dejó como "name:<nombre>" cuando el nombre es el del
+// assignee actual: es un cambio de clave, no una reasignación, así que no se
+// borra nada ni se avisa el traspaso. El archivo de storage se mueve a la
+// carpeta del accountId. Devuelve los mensajes re-asociados y el resto.
+func rekeyLegacyAssignee(
+	incident *jira.Incident,
+	previous []*database.MessageToDelete,
+	store *storage.Storage,
+	dbClient database.Repository,
+) (rekeyed, rest []*database.MessageToDelete) {
+	legacyID := database.LegacyAssigneeID(incident.Assignee)
+	if incident.AssigneeID == "" || incident.Assignee == "" {
+		return nil, previous
+	}
+	for _, msg := range previous {
+		if msg.AssigneeID == legacyID {
+			rekeyed = append(rekeyed, msg)
+		} else {
+			rest = append(rest, msg)
+		}
+	}
+	if len(rekeyed) == 0 {
+		return nil, previous
+	}
+
+	if err := dbClient.RekeyAssignee(incident.Key, legacyID, incident.AssigneeID); err != nil {
+		log.Printf(clrYellow+"Advertencia: %v"+clrReset, err)
+		return nil, previous
+	}
+	if err := store.MoveIncident(incident, legacyID); err != nil {
+		log.Printf(clrYellow+"Advertencia: %v"+clrReset, err)
+	}
+	for _, msg := range rekeyed {
+		msg.AssigneeID = incident.AssigneeID
+	}
+	log.Printf(clrCyan+"Mensajes de %s asociados al accountId de %s"+clrReset, incident.Key, incident.Assignee)
+	return rekeyed, rest
+}
+
+// planDelivery planifica las operaciones que publican la evaluación en los
+// canales de destino de la incidencia y reemplazan los mensajes que tenía
+// (existing). Con refresh (evaluación nueva) se reemplazan todos; sin refresh
+// (evaluación en caché) solo se publica en los destinos que no tienen mensaje,
+// p. ej. tras una reasignación, una entrega fallida o un cambio en las reglas de
+// ruteo; a los mensajes que ya están publicados se les planifica un
+// recordatorio si reminders lo indica (nil = sin recordatorios). Los mensajes en
+// canales que ya no son destino y los de assignees anteriores (replaced) se
+// borran. Cada mensaje anterior se borra después de los envíos, y solo si se
+// hicieron (ver outbox.Dispatcher).
+func planDelivery(
+	incident *jira.Incident,
+	eval *evaluator.EvaluationResult,
+	existing []*database.MessageToDelete,
+	replaced []*database.MessageToDelete,
+	refresh bool,
+	reminders *reminder.Policy,
+	discordClient *discord.Client,
+) ([]database.OutboxEntry, error) {
+	discordInc := convertToDiscordIncident(incident)
+	channels, destErr := discordClient.Destinations(discordInc, eval)
+
+	stale := make(map[string]*database.MessageToDelete, len(existing))
+	for _, msg := range existing {
+		stale[msg.ChannelID] = msg
+	}
+
+	var entries []database.OutboxEntry
+	var payloadErr error
+	for _, channelID := range channels {
+		old := stale[channelID]
+		if old != nil && !refresh {
+			delete(stale, channelID) // ya publicada en este canal
+			if reminders != nil && reminders.Due(old.LastNotification, old.ReminderCount, eval.AverageScore(), eval.Threshold, time.Now()) {
+				remind, err := reminderEntries(discordInc, eval, old, reminders, discordClient)
+				if err != nil {
+					payloadErr = err
+					continue
+				}
+				log.Printf(clrCyan+"Recordatorio de %s en %s (%d)"+clrReset, incident.Key, channelID, old.ReminderCount+1)
+				entries = append(entries, remind...)
+			}
+			continue
+		}
+
+		ref := database.NewOutboxRef()
+		payload, err := discordClient.EvaluationPayload(discordInc, eval, ref)
+		if err != nil {
+			payloadErr = err
+			continue
+		}
+		entries = append(entries, database.OutboxEntry{
+			Ref:         ref,
+			IncidentKey: incident.Key,
+			Operation:   database.OutboxSend,
+			ChannelID:   channelID,
+			AssigneeID:  incident.AssigneeID,
+			Assignee:    incident.Assignee,
+			Payload:     payload,
+		})
+		if old != nil {
+			entries = append(entries, deleteEntry(old))
+			delete(stale, channelID)
+		}
+	}
+
+	for _, old := range stale {
+		entries = append(entries, deleteEntry(old))
+	}
+	for _, old := range replaced {
+		entries = append(entries, deleteEntry(old))
+	}
+
+	if destErr != nil {
+		return entries, destErr
+	}
+	return entries, payloadErr
+}
+
+// deliverFromCache publica una evaluación en caché en los destinos que no tienen
+// mensaje. Devuelve cuántos mensajes envió.
+func deliverFromCache(
+	ctx context.Context,
+	incident *jira.Incident,
+	eval *evaluator.EvaluationResult,
+	existing []*database.MessageToDelete,
+	replaced []*database.MessageToDelete,
+	reminders *reminder.Policy,
+	discordClient *discord.Client,
+	dbClient database.Repository,
+	dispatcher *outbox.Dispatcher,
+) (int, error) {
+	entries, planErr := planDelivery(incident, eval, existing, replaced, false, reminders, discordClient)
+	if err := dbClient.EnqueueOutbox(entries); err != nil {
+		return 0, err
+	}
+	sent, err := dispatcher.Dispatch(ctx, incident.Key)
+	if planErr != nil && (err == nil || errors.Is(err, outbox.ErrDeferred)) {
+		return sent, planErr
+	}
+	return sent, err
+}
+
+// deleteReplaced borra los mensajes de assignees anteriores cuando no hay nada
+// que publicar porque el assignee actual ya tiene su mensaje
+func deleteReplaced(
+	ctx context.Context,
+	incident *jira.Incident,
+	replaced []*database.MessageToDelete,
+	dbClient database.Repository,
+	dispatcher *outbox.Dispatcher,
+) error {
+	if len(replaced) == 0 {
+		return nil
+	}
+	var entries []database.OutboxEntry
+	for _, old := range replaced {
+		entries = append(entries, deleteEntry(old))
+	}
+	if err := dbClient.EnqueueOutbox(entries); err != nil {
+		return err
+	}
+	if _, err := dispatcher.Dispatch(ctx, incident.Key); err != nil && !errors.Is(err, outbox.ErrDeferred) {
+		return err
+	}
+	return nil
+}
+
+// reminderEntries son las operaciones de un recordatorio sobre el mensaje old:
+// una respuesta, o volver a publicar la evaluación y borrar el mensaje anterior
+func reminderEntries(
+	incident *discord.Incident,
+	eval *evaluator.EvaluationResult,
+	old *database.MessageToDelete,
+	reminders *reminder.Policy,
+	discordClient *discord.Client,
+) ([]database.OutboxEntry, error) {
+	ref := database.NewOutboxRef()
+	operation, replyTo := database.OutboxReply, old.MessageID
+	if reminders.Mode == config.ReminderRepost {
+		operation, replyTo = database.OutboxRepost, ""
+	}
+	payload, err := discordClient.ReminderPayload(incident, eval, replyTo, old.ReminderCount+1, reminders.Max, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []database.OutboxEntry{{
+		Ref:         ref,
+		IncidentKey: old.IncidentKey,
+		Operation:   operation,
+		ChannelID:   old.ChannelID,
+		MessageID:   old.MessageID,
+		AssigneeID:  old.AssigneeID,
+		Assignee:    old.Assignee,
+		Payload:     payload,
+	}}
+	if operation == database.OutboxRepost {
+		entries = append(entries, deleteEntry(old))
+	}
+	return entries, nil
+}
+
+// cleanupRemoved borra por el outbox los mensajes de las incidencias que ya no
+// están en Jira; cada registro se quita de la BD al confirmarse su borrado
+func cleanupRemoved(ctx context.Context, currentKeys []string, dbClient database.Repository, dispatcher *outbox.Dispatcher) error {
+	removed, err := dbClient.CleanupRemovedIncidents(currentKeys)
+	if err != nil || len(removed) == 0 {
+		return err
+	}
+
+	var entries []database.OutboxEntry
+	keys := make(map[string]bool)
+	for i := range removed {
+		entries = append(entries, deleteEntry(&removed[i]))
+		keys[removed[i].IncidentKey] = true
+		log.Printf("Incidencia completada eliminada: %s (Assignee: %s)", removed[i].IncidentKey, removed[i].Assignee)
+	}
+	if err := dbClient.EnqueueOutbox(entries); err != nil {
+		return err
+	}
+
+	var errs []error
+	for key := range keys {
+		if _, err := dispatcher.Dispatch(ctx, key); err != nil && !errors.Is(err, outbox.ErrDeferred) {
+			errs = append(errs, err)
+		}
+	}
+	log.Printf("Limpieza completada: %d mensajes de incidencias completadas eliminados", len(removed))
+	return errors.Join(errs...)
+}
+
+// deleteEntry es la operación que borra un mensaje publicado
+func deleteEntry(msg *database.MessageToDelete) database.OutboxEntry {
+	return database.OutboxEntry{
+		IncidentKey: msg.IncidentKey,
+		Operation:   database.OutboxDelete,
+		ChannelID:   msg.ChannelID,
+		MessageID:   msg.MessageID,
+		AssigneeID:  msg.AssigneeID,
+		Assignee:    msg.Assignee,
+	}
+}
+
+// loadComments descarga los comentarios de la incidencia como contexto para el
+// evaluador. Un error no impide evaluar: se evalúa sin comentarios.
+func loadComments(jiraClient *jira.Client, incident *jira.Incident) {
+	if err := jiraClient.LoadComments(incident); err != nil {
+		log.Printf(clrYellow+"Advertencia: no se pudieron cargar comentarios de %s: %v"+clrReset, incident.Key, err)
+	}
+}
+
+// openDatabase conecta con la base de datos configurada. resolve traduce nombres
+// visibles a accountId en la migración 0007 (puede ser nil).
+func openDatabase(cfg *config.Config, resolve func(displayName string) (string, error)) (database.Repository, error) {
+	return database.Open(&database.Config{
+		Driver:   cfg.Database.Driver,
+		Path:     cfg.Database.Path,
+		SSLMode:  cfg.Database.SSLMode,
+		Host:     cfg.Database.Host,
+		Port:     cfg.Database.Port,
+		Username: cfg.Database.Username,
+		Password: cfg.Database.Password,
+		Database: cfg.Database.Database,
+
+		ResolveAssignee: resolve,
+	})
+}
+
+// assigneeResolver traduce nombres visibles a accountId para la migración de
+// discord_messages. Usa primero los assignees de las incidencias actuales (se
+// consultan una sola vez) y recurre a la búsqueda de usuarios de Jira para los
+// que no aparecen.
+func assigneeResolver(jiraClient *jira.Client) func(string) (string, error) {
+	var known map[string]string
+	return func(displayName string) (string, error) {
+		if known == nil {
+			known = make(map[string]string)
+			incidents, err := jiraClient.GetIncidents()
+			if err != nil {
+				log.Printf(clrYellow+"Advertencia: no se pudieron obtener incidencias para migrar assignees: %v"+clrReset, err)
+			}
+			for _, inc := range incidents {
+				if inc.Assignee != "" && inc.AssigneeID != "" {
+					known[inc.Assignee] = inc.AssigneeID
+				}
+			}
+		}
+		if accountID, ok := known[displayName]; ok {
+			return accountID, nil
+		}
+		return jiraClient.FindAccountID(displayName)
 	}
 }
 
@@ -295,7 +954,23 @@ func convertToDiscordIncident(inc *jira.Incident) *discord.Incident {
 		Status:      inc.Status,
 		IssueType:   inc.IssueType,
 		Assignee:    inc.Assignee,
+		AssigneeID:  inc.AssigneeID,
 		CreatedDate: inc.CreatedDate.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedDate: inc.UpdatedDate.Format("2006-01-02T15:04:05Z07:00"),
+		Priority:    inc.Priority,
+		Components:  inc.Components,
+		Labels:      inc.Labels,
+		Sprint:      inc.SprintName,
+		Parent:      firstNonEmpty(inc.ParentKey, inc.EpicKey),
+	}
+}
+
+// firstNonEmpty devuelve el primer valor no vacío
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
 	}
+	return ""
 }
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PhelGc/furina-sync/internal/config"
+	"github.com/PhelGc/furina-sync/internal/reminder"
+	"github.com/PhelGc/furina-sync/internal/report"
+	"github.com/PhelGc/furina-sync/internal/schedule"
+)
+
+// schedules son los horarios y las políticas que dependen de ellos, armados a
+// partir de la configuración. config guarda los valores como texto y no
+// depende de schedule ni de reminder.
+type schedules struct {
+	location  *time.Location
+	calendar  *schedule.Calendar // nil = sin horario laboral
+	sync      schedule.Spec
+	reminders *reminder.Policy // nil = sin recordatorios
+	digests   []report.Digest
+}
+
+// loadSchedules interpreta la zona horaria, el horario laboral, la frecuencia
+// de sincronización, los recordatorios y los resúmenes. Como config.Load,
+// devuelve todos los problemas juntos y no solo el primero.
+func loadSchedules(cfg *config.Config) (*schedules, error) {
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	s := &schedules{}
+
+	loc, err := schedule.LoadLocation(cfg.Schedule.Timezone)
+	if err != nil {
+		add("SCHEDULE_TIMEZONE: %v", err)
+		loc = time.Local
+	}
+	s.location = loc
+
+	if cfg.Schedule.WorkHours != "" {
+		if s.calendar, err = schedule.NewCalendar(cfg.Schedule.Timezone, cfg.Schedule.WorkDays, cfg.Schedule.WorkHours, cfg.Schedule.HolidaysFile); err != nil {
+			add("horario laboral (SCHEDULE_*): %v", err)
+		}
+	}
+	if s.sync, err = schedule.ParseSpec(cfg.Sync.Interval, loc); err != nil {
+		add("SYNC_INTERVAL_MINUTES: %v", err)
+	}
+
+	quiet, err := reminder.ParseWindow(cfg.Discord.QuietHours)
+	if err != nil {
+		add("DISCORD_QUIET_HOURS: %v", err)
+	}
+	s.reminders = reminderPolicy(cfg.Discord, quiet, loc, s.calendar)
+
+	s.digests, err = digestJobs(cfg, loc)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return s, errors.Join(errs...)
+}
+
+// reminderPolicy arma la política de recordatorios; nil si están desactivados.
+// DISCORD_QUIET_HOURS se interpreta en la zona de SCHEDULE_TIMEZONE y, con
+// horario laboral, fuera de él no hay recordatorios.
+func reminderPolicy(cfg config.DiscordConfig, quiet reminder.Window, loc *time.Location, calendar *schedule.Calendar) *reminder.Policy {
+	if cfg.RenotifyIntervalMinutes <= 0 {
+		return nil
+	}
+	return &reminder.Policy{
+		Interval:   time.Duration(cfg.RenotifyIntervalMinutes) * time.Minute,
+		Max:        cfg.ReminderMax,
+		BelowScore: cfg.ReminderBelowScore,
+		Mode:       cfg.ReminderMode,
+		Quiet:      quiet,
+		Location:   loc,
+		Calendar:   calendar,
+	}
+}
+
+// digestJobs arma los resúmenes configurados en report.digests
+func digestJobs(cfg *config.Config, loc *time.Location) ([]report.Digest, error) {
+	var digests []report.Digest
+	var errs []error
+	for i := range cfg.Report.Digests {
+		d := &cfg.Report.Digests[i]
+		spec, err := schedule.ParseSpec(d.SpecValue(), loc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resumen %s: schedule: %v", d.Name, err))
+			continue
+		}
+		title := "Resumen"
+		if d.Type == config.DigestLeaderboard {
+			title = "Ranking de calidad"
+		}
+		if d.Period == config.DigestWeekly {
+			title += " semanal"
+		} else {
+			title += " diario"
+		}
+
+		// El ranking compara los equipos; el de un equipo, solo a ese
+		teams := cfg.Report.Teams
+		if d.Team != "" {
+			title += " — " + d.Team
+			teams = map[string][]string{d.Team: cfg.Report.Teams[d.Team]}
+		}
+		digests = append(digests, report.Digest{
+			Name:       d.Name,
+			Kind:       d.Type,
+			Title:      title,
+			Channel:    d.Channel,
+			Spec:       spec,
+			Lookback:   d.Lookback(),
+			Members:    cfg.Report.Teams[d.Team],
+			Teams:      teams,
+			Worst:      d.Worst,
+			Percentile: d.Percentile,
+			Periods:    d.Periods,
+		})
+	}
+	return digests, errors.Join(errs...)
+}